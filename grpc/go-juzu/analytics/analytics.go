@@ -0,0 +1,255 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package analytics computes conversation statistics from diarization
+// results: how long and how often each speaker talks, how often speakers
+// interrupt each other, and how much of the conversation is silence.
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// DefaultMaxPause is used when Options.MaxPause is zero.
+const DefaultMaxPause = 2 * time.Second
+
+// Options configures Analyze.
+type Options struct {
+	// Duration of the conversation.  Zero takes it to end with the last
+	// segment.
+	Duration time.Duration
+
+	// MaxPause is the longest pause within a turn: a speaker who pauses
+	// for longer starts a new turn even if nobody else spoke.  Zero selects
+	// DefaultMaxPause.
+	MaxPause time.Duration
+
+	// MinInterruption is the least time a speaker must talk over another
+	// for it to count as an interruption, so as to ignore brief
+	// acknowledgements like "uh-huh".
+	MinInterruption time.Duration
+}
+
+// SpeakerStats holds the statistics of a speaker.
+type SpeakerStats struct {
+	Label string
+
+	// TalkTime is the time the speaker talks, and TalkRatio its share of
+	// the talk time of all speakers.
+	TalkTime  time.Duration
+	TalkRatio float64
+
+	// Turns is the number of turns of the speaker, the longest of which
+	// lasts LongestMonologue.
+	Turns            int
+	LongestMonologue time.Duration
+
+	// Interruptions counts the times the speaker started talking over
+	// another speaker, and Interrupted the times another speaker started
+	// talking over them.
+	Interruptions int
+	Interrupted   int
+
+	// Words is the number of words of the speaker, and WordsPerMinute the
+	// rate at which they speak them during their talk time.  Both are zero
+	// if the segments have no word timestamps.
+	Words          int
+	WordsPerMinute float64
+}
+
+// Stats holds the statistics of a conversation.
+type Stats struct {
+	Duration time.Duration
+
+	// TalkTime is the time at least one speaker talks, and OverlapTime the
+	// time several speakers talk at once.
+	TalkTime    time.Duration
+	OverlapTime time.Duration
+
+	// Silence is the time nobody talks, and SilenceShare its share of the
+	// duration.
+	Silence      time.Duration
+	SilenceShare float64
+
+	// Turns and interruptions of all speakers.
+	Turns         int
+	Interruptions int
+
+	// Speakers holds the statistics of each speaker, in order of first
+	// speech.
+	Speakers []*SpeakerStats
+}
+
+// Speaker returns the statistics of the speaker of the given label, or nil.
+func (s *Stats) Speaker(label string) *SpeakerStats {
+	for _, spk := range s.Speakers {
+		if spk.Label == label {
+			return spk
+		}
+	}
+	return nil
+}
+
+type interval struct {
+	start, end time.Duration
+}
+
+// Analyze computes the statistics of the conversation diarized in the final
+// result.  Overlapping speech is taken from the result if the server marked
+// it, and from the segment times otherwise.
+func Analyze(r *juzupb.DiarizationResult, opts Options) *Stats {
+	if opts.MaxPause == 0 {
+		opts.MaxPause = DefaultMaxPause
+	}
+
+	segs := append([]*juzupb.Segment(nil), r.Segments...)
+	sort.SliceStable(segs, func(i, j int) bool {
+		return segs[i].StartTime.AsDuration() < segs[j].StartTime.AsDuration()
+	})
+
+	stats := &Stats{Duration: opts.Duration}
+	speakers := make(map[string]*SpeakerStats)
+	intervals := make(map[string][]interval)
+	var all []interval
+
+	for _, s := range segs {
+		spk := speakers[s.SpeakerLabel]
+		if spk == nil {
+			spk = &SpeakerStats{Label: s.SpeakerLabel}
+			speakers[s.SpeakerLabel] = spk
+			stats.Speakers = append(stats.Speakers, spk)
+		}
+		spk.Words += len(s.Words)
+
+		iv := interval{s.StartTime.AsDuration(), s.EndTime.AsDuration()}
+		if iv.end <= iv.start {
+			continue
+		}
+		intervals[s.SpeakerLabel] = append(intervals[s.SpeakerLabel], iv)
+		all = append(all, iv)
+		if opts.Duration == 0 && iv.end > stats.Duration {
+			stats.Duration = iv.end
+		}
+	}
+
+	var totalTalk time.Duration
+	for _, spk := range stats.Speakers {
+		for _, iv := range union(intervals[spk.Label]) {
+			spk.TalkTime += iv.end - iv.start
+		}
+		totalTalk += spk.TalkTime
+	}
+
+	// Turns: a speaker's turn goes on while no other speaker starts a
+	// turn, and they do not pause for too long.
+	var turnSpeaker *SpeakerStats
+	var turn interval
+	endTurn := func() {
+		if turnSpeaker != nil && turn.end-turn.start > turnSpeaker.LongestMonologue {
+			turnSpeaker.LongestMonologue = turn.end - turn.start
+		}
+	}
+	for _, s := range segs {
+		spk := speakers[s.SpeakerLabel]
+		start, end := s.StartTime.AsDuration(), s.EndTime.AsDuration()
+		if spk == turnSpeaker && start-turn.end <= opts.MaxPause {
+			if end > turn.end {
+				turn.end = end
+			}
+			continue
+		}
+		if turnSpeaker != nil && spk != turnSpeaker && end <= turn.end {
+			// talking only within the turn of another speaker does not
+			// take the turn
+			continue
+		}
+		endTurn()
+		turnSpeaker, turn = spk, interval{start, end}
+		spk.Turns++
+		stats.Turns++
+	}
+	endTurn()
+
+	// Interruptions: a segment starting while another speaker is talking,
+	// and overlapping them for long enough.
+	for i, s := range segs {
+		start := s.StartTime.AsDuration()
+		interrupted := false
+		for _, o := range segs[:i] {
+			if o.SpeakerLabel == s.SpeakerLabel {
+				continue
+			}
+			overlap := minDuration(o.EndTime.AsDuration(), s.EndTime.AsDuration()) - start
+			if o.StartTime.AsDuration() < start && overlap > 0 && overlap >= opts.MinInterruption {
+				speakers[o.SpeakerLabel].Interrupted++
+				interrupted = true
+			}
+		}
+		if interrupted {
+			speakers[s.SpeakerLabel].Interruptions++
+			stats.Interruptions++
+		}
+	}
+
+	for _, spk := range stats.Speakers {
+		if totalTalk > 0 {
+			spk.TalkRatio = float64(spk.TalkTime) / float64(totalTalk)
+		}
+		if spk.Words > 0 && spk.TalkTime > 0 {
+			spk.WordsPerMinute = float64(spk.Words) / spk.TalkTime.Minutes()
+		}
+	}
+
+	for _, iv := range union(all) {
+		stats.TalkTime += iv.end - iv.start
+	}
+	for _, o := range juzu.ResultOverlaps(r) {
+		stats.OverlapTime += o.EndTime.AsDuration() - o.StartTime.AsDuration()
+	}
+	if stats.Duration > stats.TalkTime {
+		stats.Silence = stats.Duration - stats.TalkTime
+	}
+	if stats.Duration > 0 {
+		stats.SilenceShare = float64(stats.Silence) / float64(stats.Duration)
+	}
+	return stats
+}
+
+// union returns the union of the intervals, sorted.
+func union(ivs []interval) []interval {
+	ivs = append([]interval(nil), ivs...)
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].start < ivs[j].start })
+	var u []interval
+	for _, iv := range ivs {
+		if n := len(u); n > 0 && iv.start <= u[n-1].end {
+			if iv.end > u[n-1].end {
+				u[n-1].end = iv.end
+			}
+			continue
+		}
+		u = append(u, iv)
+	}
+	return u
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,99 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package analytics_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/analytics"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// segment returns a segment from start to end, in seconds, with the given
+// number of words.
+func segment(label string, start, end float64, words int) *juzupb.Segment {
+	s := &juzupb.Segment{
+		SpeakerLabel: label,
+		StartTime:    durationpb.New(time.Duration(start * float64(time.Second))),
+		EndTime:      durationpb.New(time.Duration(end * float64(time.Second))),
+	}
+	for i := 0; i < words; i++ {
+		s.Words = append(s.Words, &juzupb.WordInfo{Word: "word"})
+	}
+	return s
+}
+
+func TestAnalyze(t *testing.T) {
+	// B acknowledges A briefly, and later A interrupts B.
+	r := &juzupb.DiarizationResult{Segments: []*juzupb.Segment{
+		segment("A", 0, 10, 20),
+		segment("B", 8, 9, 0),
+		segment("A", 11, 15, 0),
+		segment("B", 16, 30, 28),
+		segment("A", 25, 32, 0),
+	}}
+	s := analytics.Analyze(r, analytics.Options{Duration: 40 * time.Second, MinInterruption: 2 * time.Second})
+
+	for _, c := range []struct {
+		what      string
+		got, want float64
+	}{
+		{"duration", s.Duration.Seconds(), 40},
+		{"talk time", s.TalkTime.Seconds(), 30},
+		{"overlap time", s.OverlapTime.Seconds(), 6},
+		{"silence", s.Silence.Seconds(), 10},
+		{"silence share", s.SilenceShare, 0.25},
+		{"turns", float64(s.Turns), 3},
+		{"interruptions", float64(s.Interruptions), 1},
+
+		{"A talk time", s.Speaker("A").TalkTime.Seconds(), 21},
+		{"A talk ratio", s.Speaker("A").TalkRatio, 21.0 / 36},
+		{"A turns", float64(s.Speaker("A").Turns), 2},
+		{"A longest monologue", s.Speaker("A").LongestMonologue.Seconds(), 15},
+		{"A interruptions", float64(s.Speaker("A").Interruptions), 1},
+		{"A interrupted", float64(s.Speaker("A").Interrupted), 0},
+		{"A words", float64(s.Speaker("A").Words), 20},
+		{"A words per minute", s.Speaker("A").WordsPerMinute, 20 / (21.0 / 60)},
+
+		{"B talk time", s.Speaker("B").TalkTime.Seconds(), 15},
+		{"B talk ratio", s.Speaker("B").TalkRatio, 15.0 / 36},
+		{"B turns", float64(s.Speaker("B").Turns), 1},
+		{"B longest monologue", s.Speaker("B").LongestMonologue.Seconds(), 14},
+		{"B interruptions", float64(s.Speaker("B").Interruptions), 0},
+		{"B interrupted", float64(s.Speaker("B").Interrupted), 1},
+		{"B words per minute", s.Speaker("B").WordsPerMinute, 112},
+	} {
+		if math.Abs(c.got-c.want) > 1e-9 {
+			t.Errorf("%s is %v; want %v", c.what, c.got, c.want)
+		}
+	}
+
+	// Without a minimum, the acknowledgement is an interruption too, and
+	// the conversation ends with the last segment.
+	s = analytics.Analyze(r, analytics.Options{})
+	if s.Interruptions != 2 || s.Speaker("B").Interruptions != 1 || s.Speaker("A").Interrupted != 1 {
+		t.Errorf("got %d interruptions, %d by B and %d of A; want 2, 1 and 1",
+			s.Interruptions, s.Speaker("B").Interruptions, s.Speaker("A").Interrupted)
+	}
+	if s.Duration != 32*time.Second || s.Silence != 2*time.Second {
+		t.Errorf("duration is %v and silence %v; want 32s and 2s", s.Duration, s.Silence)
+	}
+	if s.Speaker("C") != nil {
+		t.Errorf("unknown speaker has statistics")
+	}
+}
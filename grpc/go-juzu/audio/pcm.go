@@ -0,0 +1,47 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio
+
+import "time"
+
+// Format describes 16-bit linear PCM audio.
+type Format struct {
+	SampleRate int
+	Channels   int
+}
+
+// FrameSize returns the number of bytes taken by one sample of every channel.
+func (f Format) FrameSize() int {
+	return 2 * f.Channels
+}
+
+// Duration returns the duration of the given number of bytes of audio.
+func (f Format) Duration(bytes int64) time.Duration {
+	frames := bytes / int64(f.FrameSize())
+	rate := int64(f.SampleRate)
+
+	// split the computation to avoid overflows for long audio
+	return time.Duration(frames/rate)*time.Second +
+		time.Duration(frames%rate*int64(time.Second)/rate)
+}
+
+// Offset returns the byte offset of the frame at the given time.  The offset
+// always falls on a frame boundary.
+func (f Format) Offset(t time.Duration) int64 {
+	rate := int64(f.SampleRate)
+	sec := int64(t / time.Second)
+	frames := sec*rate + int64(t%time.Second)*rate/int64(time.Second)
+	return frames * int64(f.FrameSize())
+}
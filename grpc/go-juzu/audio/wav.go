@@ -0,0 +1,169 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audio provides helpers for the uncompressed audio formats accepted
+// by juzu server: raw 16-bit linear PCM, and the same samples in a WAV
+// container.
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+const wavFormatPCM = 1
+
+// maxFormatSize is the size of the format chunk of WAVE_FORMAT_EXTENSIBLE, the
+// largest WAV format.
+const maxFormatSize = 40
+
+// WAVHeader describes the format of the samples in a WAV file, and where they
+// are found.
+type WAVHeader struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+
+	// DataOffset is the offset (bytes) of the sample data from the start of
+	// the file, and DataSize is its size (bytes).
+	DataOffset int64
+	DataSize   int64
+}
+
+// ReadWAVHeader reads the header of a WAV file from r, up to the start of the
+// sample data.  Only uncompressed PCM data is supported.  Chunks other than
+// the format and data chunks are skipped.  Headers with no sample rate, no
+// channels, or a sample size that is not a whole number of bytes are
+// rejected.
+//
+// Streamed WAV files often do not know their size when the header is written,
+// and set it to the maximum value.  DataSize is then not reliable, and readers
+// should stop at the end of the file instead.
+func ReadWAVHeader(r io.Reader) (*WAVHeader, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("unable to read WAV header: %v", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+
+	h := &WAVHeader{}
+	offset := int64(len(riff))
+	haveFormat := false
+
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("unable to read WAV header: %v", err)
+		}
+		offset += int64(len(chunk))
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("invalid WAV format chunk of %d bytes", size)
+			}
+			// Only the fields of WAVE_FORMAT_EXTENSIBLE, the largest
+			// format, are read, and the rest of the chunk is skipped, so
+			// that the size read from the file never sets how much is
+			// allocated.
+			n := size
+			if n > maxFormatSize {
+				n = maxFormatSize
+			}
+			f := make([]byte, n)
+			if _, err := io.ReadFull(r, f); err != nil {
+				return nil, fmt.Errorf("unable to read WAV header: %v", err)
+			}
+			if _, err := io.CopyN(ioutil.Discard, r, size-n); err != nil {
+				return nil, fmt.Errorf("unable to read WAV header: %v", err)
+			}
+
+			format := binary.LittleEndian.Uint16(f[0:2])
+			h.Channels = int(binary.LittleEndian.Uint16(f[2:4]))
+			h.SampleRate = int(binary.LittleEndian.Uint32(f[4:8]))
+			blockAlign := binary.LittleEndian.Uint16(f[12:14])
+			h.BitsPerSample = int(binary.LittleEndian.Uint16(f[14:16]))
+
+			// WAVE_FORMAT_EXTENSIBLE stores the actual format in its
+			// sub-format GUID.
+			if format == 0xfffe && size >= 26 {
+				format = binary.LittleEndian.Uint16(f[24:26])
+			}
+			if format != wavFormatPCM {
+				return nil, fmt.Errorf("unsupported WAV format %d; only PCM is supported", format)
+			}
+			if h.SampleRate == 0 || h.Channels == 0 || blockAlign == 0 ||
+				h.BitsPerSample == 0 || h.BitsPerSample%8 != 0 {
+				return nil, fmt.Errorf("invalid WAV format of %d Hz, %d channels and %d bits per sample",
+					h.SampleRate, h.Channels, h.BitsPerSample)
+			}
+			haveFormat = true
+
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("WAV data chunk before format chunk")
+			}
+			h.DataOffset = offset
+			h.DataSize = size
+			return h, nil
+
+		default:
+			if _, err := io.CopyN(ioutil.Discard, r, size); err != nil {
+				return nil, fmt.Errorf("unable to read WAV header: %v", err)
+			}
+		}
+
+		// chunks are padded to an even size
+		offset += size
+		if size%2 == 1 {
+			if _, err := io.CopyN(ioutil.Discard, r, 1); err != nil {
+				return nil, fmt.Errorf("unable to read WAV header: %v", err)
+			}
+			offset++
+		}
+	}
+}
+
+// Bytes returns a canonical 44 byte header for the format and data size of h.
+// DataOffset is ignored.
+func (h *WAVHeader) Bytes() []byte {
+	b := make([]byte, 44)
+	blockAlign := h.Channels * h.BitsPerSample / 8
+
+	copy(b[0:], "RIFF")
+	binary.LittleEndian.PutUint32(b[4:], uint32(36+h.DataSize))
+	copy(b[8:], "WAVE")
+	copy(b[12:], "fmt ")
+	binary.LittleEndian.PutUint32(b[16:], 16)
+	binary.LittleEndian.PutUint16(b[20:], wavFormatPCM)
+	binary.LittleEndian.PutUint16(b[22:], uint16(h.Channels))
+	binary.LittleEndian.PutUint32(b[24:], uint32(h.SampleRate))
+	binary.LittleEndian.PutUint32(b[28:], uint32(h.SampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(b[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(b[34:], uint16(h.BitsPerSample))
+	copy(b[36:], "data")
+	binary.LittleEndian.PutUint32(b[40:], uint32(h.DataSize))
+	return b
+}
+
+// Format returns the sample format described by the header.
+func (h *WAVHeader) Format() Format {
+	return Format{SampleRate: h.SampleRate, Channels: h.Channels}
+}
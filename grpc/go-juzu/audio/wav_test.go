@@ -0,0 +1,116 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audio_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+)
+
+func TestWAVHeader(t *testing.T) {
+	h := audio.WAVHeader{SampleRate: 16000, Channels: 1, BitsPerSample: 16, DataSize: 3200}
+
+	got, err := audio.ReadWAVHeader(bytes.NewReader(h.Bytes()))
+	if err != nil {
+		t.Fatalf("could not read WAV header: %v", err)
+	}
+
+	h.DataOffset = 44
+	if *got != h {
+		t.Errorf("read WAV header %+v; want %+v", *got, h)
+	}
+
+	// Insert a LIST chunk of odd size, which is padded, between the format
+	// and data chunks.
+	b := h.Bytes()
+	list := []byte("LIST\x03\x00\x00\x00abc\x00")
+	b = append(b[:36:36], append(list, b[36:]...)...)
+
+	got, err = audio.ReadWAVHeader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("could not read WAV header with LIST chunk: %v", err)
+	}
+	if got.DataOffset != int64(44+len(list)) {
+		t.Errorf("WAV data offset with LIST chunk is %d; want %d", got.DataOffset, 44+len(list))
+	}
+
+	// Compressed formats are not supported.
+	b = h.Bytes()
+	binary.LittleEndian.PutUint16(b[20:], 3)
+	if _, err := audio.ReadWAVHeader(bytes.NewReader(b)); err == nil {
+		t.Errorf("reading header of non-PCM WAV: want error, got nil")
+	}
+
+	// Malformed formats would lead to divisions by zero.
+	for _, tc := range []struct {
+		name   string
+		offset int
+		value  uint16
+	}{
+		{"no channels", 22, 0},
+		{"no sample rate", 24, 0},
+		{"no block align", 32, 0},
+		{"no bits per sample", 34, 0},
+		{"12 bits per sample", 34, 12},
+	} {
+		b = h.Bytes()
+		binary.LittleEndian.PutUint16(b[tc.offset:], tc.value)
+		if _, err := audio.ReadWAVHeader(bytes.NewReader(b)); err == nil {
+			t.Errorf("reading WAV header with %s: want error, got nil", tc.name)
+		}
+	}
+
+	// Format chunks larger than needed are skipped, and their size does not
+	// set how much is read at once.
+	b = h.Bytes()
+	binary.LittleEndian.PutUint32(b[16:], 16+34)
+	b = append(b[:36:36], append(make([]byte, 34), b[36:]...)...)
+	got, err = audio.ReadWAVHeader(bytes.NewReader(b))
+	if err != nil || got.SampleRate != 16000 || got.DataOffset != 44+34 {
+		t.Errorf("read WAV header %+v (%v) with large format chunk", got, err)
+	}
+	b = h.Bytes()
+	binary.LittleEndian.PutUint32(b[16:], 0xffffffff)
+	if _, err := audio.ReadWAVHeader(bytes.NewReader(b)); err == nil {
+		t.Errorf("reading WAV header with truncated 4GB format chunk: want error, got nil")
+	}
+
+	if _, err := audio.ReadWAVHeader(bytes.NewReader([]byte("RIFF"))); err == nil {
+		t.Errorf("reading truncated WAV header: want error, got nil")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	f := audio.Format{SampleRate: 16000, Channels: 2}
+
+	if got := f.Duration(64000); got != time.Second {
+		t.Errorf("duration of 64000 bytes is %v; want 1s", got)
+	}
+
+	// offsets are rounded down to whole frames
+	if got := f.Offset(1500*time.Microsecond + time.Nanosecond); got != 96 {
+		t.Errorf("offset of 1.5ms is %d; want 96", got)
+	}
+
+	// 100 hours do not overflow
+	long := 100 * time.Hour
+	if got := f.Duration(f.Offset(long)); got != long {
+		t.Errorf("duration of offset of %v is %v", long, got)
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package batch diarizes many audio files, each with its own streaming call,
+// with a bounded number of calls in progress at any time.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Defaults used for zero fields of Runner.
+const (
+	DefaultConcurrency = 4
+	DefaultAttempts    = 3
+	DefaultRetryDelay  = time.Second
+)
+
+// Diarizer performs streaming diarization calls.  It is implemented by
+// *juzu.Client.
+type Diarizer interface {
+	StreamingDiarize(ctx context.Context, cfg *juzupb.DiarizationConfig, audio io.Reader,
+		handlerFunc juzu.DiarizationResponseHandler, opts ...juzu.CallOption) error
+}
+
+// Job is a single file, or other source of audio, to diarize.
+type Job struct {
+	// ID identifies the job in its batch, and must be unique.  If empty,
+	// Path is used.
+	ID string
+
+	// Path of the audio file.  Not used if Open is set.
+	Path string
+
+	// Open, if set, returns the audio to diarize.  It is called again for
+	// every attempt, so it must return the audio from its start each time.
+	Open func() (io.ReadCloser, error)
+
+	// Config of the diarization call, and the options it is made with.
+	Config  *juzupb.DiarizationConfig
+	Options []juzu.CallOption
+
+	// Attempts is the maximum number of attempts for this job.  If zero,
+	// the Runner's value is used.
+	Attempts int
+}
+
+func (j *Job) id() string {
+	if j.ID != "" {
+		return j.ID
+	}
+	return j.Path
+}
+
+func (j *Job) open() (io.ReadCloser, error) {
+	if j.Open != nil {
+		return j.Open()
+	}
+	return os.Open(j.Path)
+}
+
+// Result is the outcome of a Job.
+type Result struct {
+	Job Job
+
+	// Responses received from the last attempt at the job.
+	Responses []*juzupb.DiarizationResponse
+
+	// Err is the error the last attempt failed with, or nil if the job
+	// succeeded.
+	Err error
+
+	// Attempts is the number of attempts made at the job.
+	Attempts int
+
+	// Location where the result was stored by the Runner's Store function.
+	Location string
+
+	// Skipped is set if the job was not run because the Runner's manifest
+	// records it as done.  Location is then taken from the manifest, and
+	// there are no Responses.
+	Skipped bool
+}
+
+// Runner runs batches of jobs.  Zero values of its fields, other than Client,
+// select the defaults.
+type Runner struct {
+	Client Diarizer
+
+	// Maximum number of jobs in progress at a time.
+	Concurrency int
+
+	// Maximum number of attempts for each job, unless the job sets its own.
+	Attempts int
+
+	// Delay before the first retry of a job.  The delay doubles for each
+	// further retry.
+	RetryDelay time.Duration
+
+	// Store, if set, is called with the result of each job that succeeded,
+	// and returns the location where it stored it, such as a file path.  If
+	// it fails, so does the job.
+	Store func(Result) (string, error)
+
+	// Manifest, if set, records the state of each job as it starts and
+	// ends.  Jobs that it records as done are skipped, so that a batch
+	// that was interrupted can be resumed by running it again.
+	Manifest *Manifest
+
+	// OnResult, if set, is called as each job ends, possibly from several
+	// goroutines at once.
+	OnResult func(Result)
+}
+
+// Batch is a batch of jobs in progress.
+type Batch struct {
+	results []Result
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Start starts running the given jobs, and returns the Batch to wait on.
+// Cancelling the context cancels all jobs that have not ended yet.
+func (r *Runner) Start(ctx context.Context, jobs []Job) (*Batch, error) {
+	if r.Client == nil {
+		return nil, fmt.Errorf("batch runner has no client")
+	}
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	b := &Batch{
+		results: make([]Result, len(jobs)),
+		cancels: make(map[string]context.CancelFunc, len(jobs)),
+	}
+	// All IDs are checked before any context is created, so that none is
+	// left uncancelled when the jobs are rejected.
+	seen := make(map[string]bool, len(jobs))
+	for i := range jobs {
+		id := jobs[i].id()
+		if id == "" {
+			return nil, fmt.Errorf("job %d has neither ID nor path", i)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate job ID %q", id)
+		}
+		seen[id] = true
+	}
+	ctxs := make([]context.Context, len(jobs))
+	for i := range jobs {
+		ctxs[i], b.cancels[jobs[i].id()] = context.WithCancel(ctx)
+	}
+
+	// Jobs are taken from the queue by a fixed number of workers, in order.
+	queue := make(chan int)
+	go func() {
+		defer close(queue)
+		for i := range jobs {
+			queue <- i
+		}
+	}()
+
+	b.wg.Add(concurrency)
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			defer b.wg.Done()
+			for i := range queue {
+				res := r.process(ctxs[i], jobs[i])
+				b.cancels[jobs[i].id()]()
+				b.results[i] = res
+				if r.OnResult != nil {
+					r.OnResult(res)
+				}
+			}
+		}()
+	}
+
+	return b, nil
+}
+
+// Run runs the given jobs, and returns their results in the same order.
+func (r *Runner) Run(ctx context.Context, jobs []Job) ([]Result, error) {
+	b, err := r.Start(ctx, jobs)
+	if err != nil {
+		return nil, err
+	}
+	return b.Wait(), nil
+}
+
+// Cancel cancels the job with the given ID.  Its result then has the error
+// of the cancelled call, unless it had already ended.
+func (b *Batch) Cancel(id string) {
+	if cancel, ok := b.cancels[id]; ok {
+		cancel()
+	}
+}
+
+// Wait waits for all jobs to end, and returns their results in the order of
+// the jobs.
+func (b *Batch) Wait() []Result {
+	b.wg.Wait()
+	return b.results
+}
+
+// process runs a job unless the manifest records it as done, stores its
+// result, and records it in the manifest.
+func (r *Runner) process(ctx context.Context, job Job) Result {
+	id := job.id()
+	if r.Manifest != nil {
+		if e, ok := r.Manifest.Entry(id); ok && e.State == StateDone {
+			return Result{Job: job, Location: e.Location, Skipped: true}
+		}
+		if err := r.Manifest.Record(Entry{ID: id, State: StateRunning}); err != nil {
+			return Result{Job: job, Err: err}
+		}
+	}
+
+	res := r.run(ctx, job)
+	if res.Err == nil && r.Store != nil {
+		if res.Location, res.Err = r.Store(res); res.Err != nil {
+			res.Err = fmt.Errorf("unable to store result: %v", res.Err)
+		}
+	}
+
+	if r.Manifest != nil {
+		e := Entry{ID: id, State: StateDone, Location: res.Location, Attempts: res.Attempts}
+		if res.Err != nil {
+			e.State, e.Error = StateFailed, res.Err.Error()
+		}
+		if err := r.Manifest.Record(e); err != nil && res.Err == nil {
+			res.Err = err
+		}
+	}
+	return res
+}
+
+// run runs a job, retrying it until it succeeds, it runs out of attempts, or
+// its context is done.
+func (r *Runner) run(ctx context.Context, job Job) Result {
+	attempts := job.Attempts
+	if attempts <= 0 {
+		attempts = r.Attempts
+	}
+	if attempts <= 0 {
+		attempts = DefaultAttempts
+	}
+	delay := r.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	res := Result{Job: job}
+	for {
+		res.Attempts++
+		res.Responses, res.Err = r.attempt(ctx, &job)
+		if res.Err == nil || res.Attempts >= attempts || ctx.Err() != nil {
+			return res
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return res
+		}
+		delay *= 2
+	}
+}
+
+// attempt makes a single diarization call for the job.
+func (r *Runner) attempt(ctx context.Context, job *Job) ([]*juzupb.DiarizationResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	audio, err := job.open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audio: %v", err)
+	}
+	defer audio.Close()
+
+	var responses []*juzupb.DiarizationResponse
+	err = r.Client.StreamingDiarize(ctx, job.Config, audio, func(resp *juzupb.DiarizationResponse) {
+		responses = append(responses, resp)
+	}, job.Options...)
+	if err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
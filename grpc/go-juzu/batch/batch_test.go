@@ -0,0 +1,171 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/batch"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// mockDiarizer returns the audio as the transcript of a single segment.  Audio
+// starting with "fail" fails as many times as the number that follows, and
+// audio starting with "block" blocks until the call is cancelled.
+type mockDiarizer struct {
+	mu       sync.Mutex
+	running  int
+	maxCalls int
+	failures map[string]int
+}
+
+func (m *mockDiarizer) StreamingDiarize(ctx context.Context, cfg *juzupb.DiarizationConfig, audio io.Reader,
+	handlerFunc juzu.DiarizationResponseHandler, opts ...juzu.CallOption) error {
+
+	m.mu.Lock()
+	m.running++
+	if m.running > m.maxCalls {
+		m.maxCalls = m.running
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.running--
+		m.mu.Unlock()
+	}()
+
+	b, err := ioutil.ReadAll(audio)
+	if err != nil {
+		return err
+	}
+	data := string(b)
+
+	switch {
+	case strings.HasPrefix(data, "fail"):
+		times, err := strconv.Atoi(data[len("fail"):])
+		if err != nil {
+			return fmt.Errorf("invalid mock audio %q: %v", data, err)
+		}
+		m.mu.Lock()
+		n := m.failures[data]
+		m.failures[data]++
+		m.mu.Unlock()
+		if n < times {
+			return fmt.Errorf("streaming diarization failed: attempt %d", n+1)
+		}
+	case data == "block":
+		<-ctx.Done()
+		return fmt.Errorf("streaming diarization failed: %v", ctx.Err())
+	default:
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	handlerFunc(&juzupb.DiarizationResponse{Results: []*juzupb.DiarizationResult{{
+		Segments: []*juzupb.Segment{{Transcript: data}},
+	}}})
+	return nil
+}
+
+func job(id, audio string) batch.Job {
+	return batch.Job{
+		ID:     id,
+		Config: &juzupb.DiarizationConfig{},
+		Open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(audio)), nil
+		},
+	}
+}
+
+func TestRunner(t *testing.T) {
+	m := &mockDiarizer{failures: map[string]int{}}
+	var mu sync.Mutex
+	ended := 0
+	r := &batch.Runner{
+		Client:      m,
+		Concurrency: 3,
+		RetryDelay:  time.Millisecond,
+		OnResult: func(batch.Result) {
+			mu.Lock()
+			ended++
+			mu.Unlock()
+		},
+	}
+
+	var jobs []batch.Job
+	for i := 0; i < 10; i++ {
+		jobs = append(jobs, job(fmt.Sprint(i), fmt.Sprintf("audio%d", i)))
+	}
+	jobs = append(jobs, job("retried", "fail2"), job("failed", "fail5"), job("cancelled", "block"))
+	jobs[len(jobs)-2].Attempts = 2
+	jobs = append(jobs, batch.Job{Path: "/nonexistent/audio.wav", Config: &juzupb.DiarizationConfig{}})
+
+	b, err := r.Start(context.Background(), jobs)
+	if err != nil {
+		t.Fatalf("could not start batch: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		b.Cancel("cancelled")
+	}()
+	results := b.Wait()
+
+	if len(results) != len(jobs) {
+		t.Fatalf("got %d results for %d jobs", len(results), len(jobs))
+	}
+	for i, res := range results[:10] {
+		if res.Err != nil || res.Attempts != 1 || len(res.Responses) != 1 {
+			t.Errorf("job %d: got error %v after %d attempts with %d responses; want success after 1 attempt",
+				i, res.Err, res.Attempts, len(res.Responses))
+			continue
+		}
+		if got, want := res.Responses[0].Results[0].Segments[0].Transcript, fmt.Sprintf("audio%d", i); got != want {
+			t.Errorf("job %d: got result for %q; want %q", i, got, want)
+		}
+	}
+
+	if res := results[10]; res.Err != nil || res.Attempts != 3 {
+		t.Errorf("retried job: got error %v after %d attempts; want success after 3 attempts", res.Err, res.Attempts)
+	}
+	if res := results[11]; res.Err == nil || res.Attempts != 2 {
+		t.Errorf("failed job: got error %v after %d attempts; want error after 2 attempts", res.Err, res.Attempts)
+	}
+	if res := results[12]; res.Err == nil || res.Attempts != 1 {
+		t.Errorf("cancelled job: got error %v after %d attempts; want error after 1 attempt", res.Err, res.Attempts)
+	}
+	if res := results[13]; res.Err == nil {
+		t.Errorf("job of missing file: want error, got nil")
+	}
+
+	if m.maxCalls > r.Concurrency {
+		t.Errorf("batch made %d concurrent calls; want at most %d", m.maxCalls, r.Concurrency)
+	}
+	if ended != len(jobs) {
+		t.Errorf("OnResult was called for %d jobs; want %d", ended, len(jobs))
+	}
+
+	// job IDs must be unique
+	if _, err := r.Run(context.Background(), []batch.Job{job("a", "x"), job("a", "y")}); err == nil {
+		t.Errorf("running batch with duplicate job IDs: want error, got nil")
+	}
+}
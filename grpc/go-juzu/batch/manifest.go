@@ -0,0 +1,167 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// State is the state of a job recorded in a Manifest.
+type State string
+
+// Job states.  A job that is still running when its manifest is reopened was
+// interrupted, and is run again just like a failed job.
+const (
+	StateRunning State = "running"
+	StateDone    State = "done"
+	StateFailed  State = "failed"
+)
+
+// Entry is the record of a job in a Manifest.
+type Entry struct {
+	ID       string    `json:"id"`
+	State    State     `json:"state"`
+	Location string    `json:"location,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Attempts int       `json:"attempts,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// Manifest records the state of the jobs of a batch in a file on local disk,
+// so that a batch that was interrupted can be resumed without running again
+// the jobs that were already done.
+//
+// The file holds one JSON encoded Entry per line, and is only ever appended
+// to while it is open, with each line synced to disk before Record returns.
+// When a job is recorded several times, its last entry wins.
+type Manifest struct {
+	mu      sync.Mutex
+	f       *os.File
+	entries map[string]Entry
+}
+
+// OpenManifest opens the manifest at the given path, creating it if it does
+// not exist.  A last line left incomplete by a crash is ignored.  The file is
+// compacted to hold a single entry per job.
+func OpenManifest(path string) (*Manifest, error) {
+	m := &Manifest{entries: make(map[string]Entry)}
+	var order []string
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to read manifest: %v", err)
+	}
+
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil || e.ID == "" {
+			if i == len(lines)-1 {
+				break // incomplete last line
+			}
+			return nil, fmt.Errorf("invalid manifest entry on line %d", i+1)
+		}
+		if _, ok := m.entries[e.ID]; !ok {
+			order = append(order, e.ID)
+		}
+		m.entries[e.ID] = e
+	}
+
+	// Write the compacted manifest next to the old one, and replace it
+	// only once it is safely on disk.
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write manifest: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	for _, id := range order {
+		if err := writeEntry(w, m.entries[id]); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("unable to write manifest: %v", err)
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to write manifest: %v", err)
+	}
+
+	m.f, err = os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open manifest: %v", err)
+	}
+	return m, nil
+}
+
+func writeEntry(w io.Writer, e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// Entry returns the last recorded entry of the job with the given ID.
+func (m *Manifest) Entry(id string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[id]
+	return e, ok
+}
+
+// Record appends the given entry to the manifest, and syncs it to disk.  The
+// entry's time is set to the current time if it is zero.
+func (m *Manifest) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := writeEntry(m.f, e); err != nil {
+		return fmt.Errorf("unable to record manifest entry: %v", err)
+	}
+	if err := m.f.Sync(); err != nil {
+		return fmt.Errorf("unable to record manifest entry: %v", err)
+	}
+	m.entries[e.ID] = e
+	return nil
+}
+
+// Close closes the manifest file.
+func (m *Manifest) Close() error {
+	return m.f.Close()
+}
@@ -0,0 +1,118 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/batch"
+)
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "manifest.jsonl")
+
+	m := &mockDiarizer{failures: map[string]int{}}
+	stored := map[string]int{}
+	r := &batch.Runner{
+		Client: m,
+		Store: func(res batch.Result) (string, error) {
+			stored[res.Job.ID]++
+			return "results/" + res.Job.ID, nil
+		},
+	}
+	jobs := []batch.Job{job("a", "audio"), job("b", "fail1"), job("c", "audio"), job("d", "audio")}
+	jobs[1].Attempts = 1
+
+	manifest, err := batch.OpenManifest(path)
+	if err != nil {
+		t.Fatalf("could not open manifest: %v", err)
+	}
+	r.Concurrency = 1
+	r.Manifest = manifest
+	if _, err := r.Run(context.Background(), jobs[:2]); err != nil {
+		t.Fatalf("could not run batch: %v", err)
+	}
+	if err := manifest.Close(); err != nil {
+		t.Fatalf("could not close manifest: %v", err)
+	}
+
+	// Simulate a crash while job c was running and its entry being written.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("could not open manifest file: %v", err)
+	}
+	_, _ = f.WriteString(`{"id":"c","state":"running","time":"2021-01-01T00:00:00Z"}` + "\n" + `{"id":"c","sta`)
+	_ = f.Close()
+
+	manifest, err = batch.OpenManifest(path)
+	if err != nil {
+		t.Fatalf("could not reopen manifest: %v", err)
+	}
+	if e, ok := manifest.Entry("b"); !ok || e.State != batch.StateFailed || e.Error == "" {
+		t.Errorf("manifest entry of failed job is %+v", e)
+	}
+	if e, ok := manifest.Entry("c"); !ok || e.State != batch.StateRunning {
+		t.Errorf("manifest entry of interrupted job is %+v", e)
+	}
+
+	r.Manifest = manifest
+	results, err := r.Run(context.Background(), jobs)
+	if err != nil {
+		t.Fatalf("could not resume batch: %v", err)
+	}
+
+	if res := results[0]; !res.Skipped || res.Location != "results/a" {
+		t.Errorf("resumed batch did not skip job that was done: %+v", res)
+	}
+	for _, res := range results[1:] {
+		if res.Skipped || res.Err != nil {
+			t.Errorf("resumed batch: job %s skipped: %v, error: %v; want success", res.Job.ID, res.Skipped, res.Err)
+		}
+		if e, _ := manifest.Entry(res.Job.ID); e.State != batch.StateDone || e.Location != "results/"+res.Job.ID {
+			t.Errorf("manifest entry of job %s is %+v", res.Job.ID, e)
+		}
+	}
+	for id, n := range stored {
+		if n != 1 {
+			t.Errorf("result of job %s was stored %d times; want once", id, n)
+		}
+	}
+
+	// the manifest was compacted to one entry per job
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read manifest file: %v", err)
+	}
+	if err := manifest.Close(); err != nil {
+		t.Fatalf("could not close manifest: %v", err)
+	}
+	if manifest, err = batch.OpenManifest(path); err != nil {
+		t.Fatalf("could not reopen manifest: %v", err)
+	}
+	defer manifest.Close()
+	b2, _ := ioutil.ReadFile(path)
+	if len(b2) >= len(b) {
+		t.Errorf("manifest of %d bytes was not compacted; got %d bytes", len(b), len(b2))
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bleep silences time ranges of 16-bit linear PCM audio, raw or in a
+// WAV file, or replaces them with a tone, such as to remove words redacted
+// from transcripts with package redact.
+//
+// Audio is streamed through, and keeps its length, format and, for WAV
+// files, its header.
+package bleep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+)
+
+// Defaults used for zero fields of Options.
+const (
+	DefaultFrequency = 1000
+	DefaultAmplitude = 0.25
+)
+
+// Range is a time range of audio, from the beginning of the audio.
+type Range struct {
+	Start, End time.Duration
+}
+
+// Mode sets what replaces the audio of the ranges.
+type Mode int
+
+const (
+	// Mute silences the audio.
+	Mute Mode = iota
+
+	// Tone replaces the audio with a sine tone.
+	Tone
+)
+
+// Options configures how ranges are bleeped.
+type Options struct {
+	Mode Mode
+
+	// Frequency, in Hz, and amplitude, as a fraction of full scale, of the
+	// tone.  Zero values select the defaults.
+	Frequency float64
+	Amplitude float64
+}
+
+func (o *Options) setDefaults() error {
+	if o.Frequency == 0 {
+		o.Frequency = DefaultFrequency
+	}
+	if o.Amplitude == 0 {
+		o.Amplitude = DefaultAmplitude
+	}
+	if o.Mode != Mute && o.Mode != Tone {
+		return fmt.Errorf("invalid bleep mode %d", o.Mode)
+	}
+	if o.Frequency < 0 || o.Amplitude < 0 || o.Amplitude > 1 {
+		return fmt.Errorf("invalid tone of %v Hz at amplitude %v", o.Frequency, o.Amplitude)
+	}
+	return nil
+}
+
+// frameRange is a range of frames, [start, end).
+type frameRange struct {
+	start, end int64
+}
+
+// frameRanges returns the frames of the ranges, sorted and merged.  Ranges
+// are widened to whole frames.
+func frameRanges(ranges []Range, f audio.Format) []frameRange {
+	fs := int64(f.FrameSize())
+	var frs []frameRange
+	for _, r := range ranges {
+		start := f.Offset(r.Start) / fs
+		end := f.Offset(r.End) / fs
+		if f.Duration(end*fs) < r.End {
+			end++
+		}
+		if end > start {
+			frs = append(frs, frameRange{start, end})
+		}
+	}
+	sort.Slice(frs, func(i, j int) bool { return frs[i].start < frs[j].start })
+
+	var merged []frameRange
+	for _, fr := range frs {
+		if n := len(merged); n > 0 && fr.start <= merged[n-1].end {
+			if fr.end > merged[n-1].end {
+				merged[n-1].end = fr.end
+			}
+			continue
+		}
+		merged = append(merged, fr)
+	}
+	return merged
+}
+
+// PCM copies raw 16-bit little-endian PCM audio of the given format from r to
+// w, with the ranges bleeped.
+func PCM(w io.Writer, r io.Reader, format audio.Format, ranges []Range, opts Options) error {
+	if err := opts.setDefaults(); err != nil {
+		return err
+	}
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	return bleep(w, r, format, frameRanges(ranges, format), opts)
+}
+
+// checkFormat returns an error for formats without samples, with which
+// frames would be empty.
+func checkFormat(f audio.Format) error {
+	if f.SampleRate <= 0 || f.Channels <= 0 {
+		return fmt.Errorf("invalid audio format %+v", f)
+	}
+	return nil
+}
+
+// WAV copies a WAV file from r to w, with the ranges bleeped.  The header and
+// any chunks after the sample data are copied unchanged.  Only 16-bit PCM
+// data is supported.
+func WAV(w io.Writer, r io.Reader, ranges []Range, opts Options) error {
+	if err := opts.setDefaults(); err != nil {
+		return err
+	}
+
+	var header bytes.Buffer
+	h, err := audio.ReadWAVHeader(io.TeeReader(r, &header))
+	if err != nil {
+		return err
+	}
+	if h.BitsPerSample != 16 {
+		return fmt.Errorf("unsupported WAV sample size of %d bits; only 16 bits are supported", h.BitsPerSample)
+	}
+	format := h.Format()
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	// Streamed files may not know their data size; their data then runs to
+	// the end of the file.
+	data := r
+	if h.DataSize < math.MaxUint32 {
+		data = io.LimitReader(r, h.DataSize)
+	}
+	if err := bleep(w, data, format, frameRanges(ranges, format), opts); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// bleep copies the samples from r to w, replacing the frames of the ranges.
+func bleep(w io.Writer, r io.Reader, f audio.Format, ranges []frameRange, opts Options) error {
+	const framesPerBuffer = 4096
+	fs := f.FrameSize()
+	buf := make([]byte, framesPerBuffer*fs)
+	amplitude := opts.Amplitude * math.MaxInt16
+	step := 2 * math.Pi * opts.Frequency / float64(f.SampleRate)
+
+	var frame int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+
+		// A trailing partial frame is copied as it is.
+		for i := 0; i+fs <= n; i, frame = i+fs, frame+1 {
+			for len(ranges) > 0 && ranges[0].end <= frame {
+				ranges = ranges[1:]
+			}
+			if len(ranges) == 0 || frame < ranges[0].start {
+				continue
+			}
+
+			var sample int16
+			if opts.Mode == Tone {
+				sample = int16(math.Round(amplitude * math.Sin(step*float64(frame))))
+			}
+			for c := 0; c < f.Channels; c++ {
+				binary.LittleEndian.PutUint16(buf[i+2*c:], uint16(sample))
+			}
+		}
+
+		if _, werr := w.Write(buf[:n]); werr != nil {
+			return werr
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bleep_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/bleep"
+)
+
+// pcm returns the given number of frames of constant samples.
+func pcm(frames, channels int) []byte {
+	b := make([]byte, 2*frames*channels)
+	for i := 0; i < len(b); i += 2 {
+		binary.LittleEndian.PutUint16(b[i:], 1000)
+	}
+	return b
+}
+
+func sample(b []byte, frame, channels, c int) int16 {
+	return int16(binary.LittleEndian.Uint16(b[2*(frame*channels+c):]))
+}
+
+var ranges = []bleep.Range{
+	{100 * time.Millisecond, 200 * time.Millisecond},
+	{150 * time.Millisecond, 300 * time.Millisecond},
+	{900 * time.Millisecond, 2 * time.Second},
+}
+
+// bleeped tells whether the frame at 8kHz is in the ranges.
+func bleeped(frame int) bool {
+	return frame >= 800 && frame < 2400 || frame >= 7200
+}
+
+func TestPCM(t *testing.T) {
+	const channels = 2
+	f := audio.Format{SampleRate: 8000, Channels: channels}
+	in := append(pcm(8000, channels), 1) // one trailing byte
+
+	for _, mode := range []bleep.Mode{bleep.Mute, bleep.Tone} {
+		var out bytes.Buffer
+		if err := bleep.PCM(&out, bytes.NewReader(in), f, ranges, bleep.Options{Mode: mode}); err != nil {
+			t.Fatalf("mode %d: %v", mode, err)
+		}
+		b := out.Bytes()
+		if len(b) != len(in) || b[len(b)-1] != 1 {
+			t.Fatalf("mode %d: got %d bytes; want %d", mode, len(b), len(in))
+		}
+
+		for i := 0; i < 8000; i++ {
+			want := int16(1000)
+			if bleeped(i) {
+				want = 0
+				if mode == bleep.Tone {
+					want = int16(math.Round(0.25 * math.MaxInt16 * math.Sin(2*math.Pi*float64(i)/8)))
+				}
+			}
+			for c := 0; c < channels; c++ {
+				if got := sample(b, i, channels, c); got != want {
+					t.Fatalf("mode %d: sample %d of channel %d is %d; want %d", mode, i, c, got, want)
+				}
+			}
+		}
+	}
+
+	if err := bleep.PCM(&bytes.Buffer{}, bytes.NewReader(in), f, ranges, bleep.Options{Amplitude: 2}); err == nil {
+		t.Errorf("amplitude of 2: want error, got nil")
+	}
+	for _, bad := range []audio.Format{{SampleRate: 8000}, {Channels: 1}} {
+		if err := bleep.PCM(&bytes.Buffer{}, bytes.NewReader(in), bad, ranges, bleep.Options{}); err == nil {
+			t.Errorf("format %+v: want error, got nil", bad)
+		}
+	}
+}
+
+func TestWAV(t *testing.T) {
+	data := pcm(8000, 1)
+	h := audio.WAVHeader{SampleRate: 8000, Channels: 1, BitsPerSample: 16, DataSize: int64(len(data))}
+
+	// a LIST chunk before the data, and another after it
+	list := []byte("LIST\x04\x00\x00\x00abcd")
+	header := h.Bytes()
+	header = append(header[:36:36], append(list, header[36:]...)...)
+	in := append(append(append([]byte(nil), header...), data...), list...)
+
+	var out bytes.Buffer
+	if err := bleep.WAV(&out, bytes.NewReader(in), ranges, bleep.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	b := out.Bytes()
+	if len(b) != len(in) || !bytes.Equal(b[:len(header)], header) || !bytes.Equal(b[len(b)-len(list):], list) {
+		t.Fatalf("header or trailing chunk changed")
+	}
+	samples := b[len(header) : len(header)+len(data)]
+	for i := 0; i < 8000; i++ {
+		if got, muted := sample(samples, i, 1, 0), bleeped(i); muted != (got == 0) {
+			t.Fatalf("sample %d is %d", i, got)
+		}
+	}
+
+	h.BitsPerSample = 8
+	if err := bleep.WAV(&bytes.Buffer{}, bytes.NewReader(h.Bytes()), ranges, bleep.Options{}); err == nil {
+		t.Errorf("8-bit WAV: want error, got nil")
+	}
+
+	// headers without channels or sample rate are rejected, instead of
+	// looping over empty frames or dividing by zero
+	for _, bad := range []audio.WAVHeader{
+		{SampleRate: 8000, BitsPerSample: 16, DataSize: int64(len(data))},
+		{Channels: 1, BitsPerSample: 16, DataSize: int64(len(data))},
+	} {
+		in := append(bad.Bytes(), data...)
+		if err := bleep.WAV(&bytes.Buffer{}, bytes.NewReader(in), ranges, bleep.Options{}); err == nil {
+			t.Errorf("WAV of %d Hz and %d channels: want error, got nil", bad.SampleRate, bad.Channels)
+		}
+	}
+}
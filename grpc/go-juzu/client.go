@@ -25,10 +25,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/flac"
 	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/vad"
+	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/empty"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
 const defaultStreamingBufSize uint32 = 8192
@@ -38,12 +42,16 @@ const defaultConnectTimeout = 2 * time.Second
 //
 // All methods except Close may be called concurrently.
 type Client struct {
-	conn             *grpc.ClientConn
-	juzu             juzupb.JuzuClient
-	insecure         bool
-	tlsCfg           tls.Config
-	streamingBufSize uint32
-	connectTimeout   time.Duration
+	conn                   *grpc.ClientConn
+	juzu                   juzupb.JuzuClient
+	insecure               bool
+	tlsCfg                 tls.Config
+	streamingBufSize       uint32
+	streamingChunkDuration time.Duration
+	connectTimeout         time.Duration
+	limiter                *rateLimiter
+	flacEncoding           bool
+	gzip                   bool
 }
 
 // NewClient creates a new Client that connects to a juzu Server listening on
@@ -68,10 +76,15 @@ func NewClient(addr string, opts ...Option) (*Client, error) {
 		dopt = grpc.WithTransportCredentials(credentials.NewTLS(&c.tlsCfg))
 	}
 
+	dopts := []grpc.DialOption{dopt, grpc.WithBlock()}
+	if c.gzip {
+		dopts = append(dopts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), c.connectTimeout)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, addr, dopt, grpc.WithBlock())
+	conn, err := grpc.DialContext(ctx, addr, dopts...)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create a client: %v", err)
 	}
@@ -137,6 +150,27 @@ func WithStreamingBufferSize(n uint32) Option {
 	}
 }
 
+// WithStreamingChunkDuration returns an Option that sizes each audio message
+// sent from the Client to the server during streaming GRPC calls by duration
+// instead of bytes.  The number of bytes is computed from the sample rate in
+// the DiarizationConfig of each call, which is then required, and every
+// message except the last holds exactly that many whole 16-bit samples.
+//
+// This applies to the RAW_LINEAR16 encoding only, whose samples are 16-bit
+// mono.  Audio in other encodings, including WAV, whose sample format is only
+// known from its header, is still sent in messages of the streaming buffer
+// size.
+// A duration d>0 is required.
+func WithStreamingChunkDuration(d time.Duration) Option {
+	return func(c *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid streaming chunk duration of %v", d)
+		}
+		c.streamingChunkDuration = d
+		return nil
+	}
+}
+
 // WithConnectTimeout returns an Option that configures the timeout for
 // establishing grpc connection with the server.  Use this only when you are on
 // a slow network and when Cobalt recommends you to do so.
@@ -147,6 +181,77 @@ func WithConnectTimeout(t time.Duration) Option {
 	}
 }
 
+// WithBandwidthLimit returns an Option that limits the rate (bytes/second) at
+// which audio is uploaded to the server.  The limit is shared by all streaming
+// calls made with this Client, and concurrent streams take turns so that each
+// gets a fair share of it.  A value n>0 is required.
+func WithBandwidthLimit(bytesPerSec uint32) Option {
+	return func(c *Client) error {
+		if bytesPerSec == 0 {
+			return fmt.Errorf("invalid bandwidth limit of 0")
+		}
+		c.limiter = newRateLimiter(bytesPerSec)
+		return nil
+	}
+}
+
+// WithFLACEncoding returns an Option that makes the Client compress audio
+// before uploading it.  Audio of streaming calls whose DiarizationConfig uses
+// the RAW_LINEAR16 encoding is encoded to FLAC on the fly, and the config sent
+// to the server is changed to the FLAC encoding.  The DiarizationConfig must
+// then specify the sample rate of the audio.  Audio in other encodings is sent
+// unchanged.  Use this on slow or expensive network links, with servers that
+// support the FLAC encoding.
+func WithFLACEncoding() Option {
+	return func(c *Client) error {
+		c.flacEncoding = true
+		return nil
+	}
+}
+
+// WithGzipCompression returns an Option that enables gzip compression of all
+// GRPC messages exchanged with the server.
+func WithGzipCompression() Option {
+	return func(c *Client) error {
+		c.gzip = true
+		return nil
+	}
+}
+
+// CallOption configures a single streaming call made with the Client.
+type CallOption func(*callConfig) error
+
+// callConfig holds the settings of a single streaming call.
+type callConfig struct {
+	limiter *rateLimiter
+	trim    *vad.Config
+}
+
+// WithCallBandwidthLimit returns a CallOption that limits the rate
+// (bytes/second) at which audio is uploaded by a single call.  If the Client
+// was also created with WithBandwidthLimit, both limits apply.  A value n>0 is
+// required.
+func WithCallBandwidthLimit(bytesPerSec uint32) CallOption {
+	return func(cc *callConfig) error {
+		if bytesPerSec == 0 {
+			return fmt.Errorf("invalid bandwidth limit of 0")
+		}
+		cc.limiter = newRateLimiter(bytesPerSec)
+		return nil
+	}
+}
+
+// WithSilenceTrimming returns a CallOption that removes long silences from the
+// audio before it is sent, as configured by cfg, and maps the timestamps of
+// all results back to the original audio before they are passed to the
+// handler.  It requires RAW_LINEAR16 audio with a sample rate.
+func WithSilenceTrimming(cfg vad.Config) CallOption {
+	return func(cc *callConfig) error {
+		cc.trim = &cfg
+		return nil
+	}
+}
+
 // Close closes the connection to the API service.  The user should only invoke
 // this when the client is no longer needed.  Pending or in-progress calls to
 // other methods may fail with an error if Close is called, and any subsequent
@@ -178,7 +283,9 @@ type DiarizationResponseHandler func(*juzupb.DiarizationResponse)
 //
 // Data is read from the given audio reader into a buffer and streamed to Juzu
 // server.  The default buffer size may be overridden using Options when
-// creating the Client.
+// creating the Client.  When the reader returns io.EOF, the end of the audio is
+// signalled to the server, which then completes the diarization.  To push
+// audio to the server instead, use NewAudioStream.
 //
 // After results are received from the Juzu server, they will be sent to the
 // provided handlerFunc.
@@ -186,6 +293,9 @@ type DiarizationResponseHandler func(*juzupb.DiarizationResponse)
 // If any error occurs while reading the audio or sending it to the server, this
 // method will immediately exit, returning that error.
 //
+// CallOptions may be given to override settings for this call only, such as
+// limiting its upload bandwidth.
+//
 // This function returns only after all results have been passed to the
 // resultHandler.
 func (c *Client) StreamingDiarize(
@@ -193,103 +303,79 @@ func (c *Client) StreamingDiarize(
 	cfg *juzupb.DiarizationConfig,
 	audio io.Reader,
 	handlerFunc DiarizationResponseHandler,
+	opts ...CallOption,
 ) error {
 
-	stream, err := c.juzu.StreamingDiarize(ctx)
+	s, cfg, err := c.openAudioStream(ctx, cfg, opts)
 	if err != nil {
-		return fmt.Errorf("unable to start streaming diarization: %v", err)
+		return err
 	}
 
 	// There are two concurrent processes going on.  We will create a new
 	// goroutine to read audio and stream it to the server.  This goroutine
 	// will receive results from the stream.  Errors could occur in both
-	// goroutines.  We therefore setup a channel, errCh, to hold these
-	// errors. Both goroutines are designed to send up to one error, and
-	// return immediately. Therefore we use a bufferred channel with a
-	// capacity of two.
-	errCh := make(chan error, 2)
+	// goroutines.  The receiving side records its error in the AudioStream,
+	// and the sending goroutine sends up to one error on errCh, and returns
+	// immediately.
+	errCh := make(chan error, 1)
 
 	// start streaming audio in a separate goroutine
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		if err := sendaudio(
-			stream, cfg, audio, c.streamingBufSize,
-		); err != nil && err != io.EOF {
-			// if sendaudio encountered io.EOF, it's only a
+		err := s.sendConfig(cfg)
+		if err == nil {
+			err = sendaudio(s, audio, c.streamingBufSize)
+		}
+		if err != nil && err != io.EOF {
+			// if sending encountered io.EOF, it's only a
 			// notification that the stream has closed.  The actual
 			// status will be obtained in a subsequent Recv call, in
-			// the other goroutine below.  We therefore only forward
-			// non-EOF errors.
+			// the receiving goroutine below.  We therefore only
+			// forward non-EOF errors.
 			errCh <- err
 		}
 		wg.Done()
 	}()
 
-	for {
-		in, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			errCh <- err
-			break
-		}
-
-		handlerFunc(in)
-	}
+	s.receive(handlerFunc)
 
 	wg.Wait()
 
+	// If both sides failed, it is very likely the errors are related (e.g.
+	// connection reset causing both the send and recv to fail) and we
+	// therefore return the first one and discard the other.
+	if s.err != nil {
+		return fmt.Errorf("streaming diarization failed: %v", s.err)
+	}
 	select {
 	case err := <-errCh:
-		// There may be more than one error in the channel, but it is
-		// very likely they are related (e.g. connection reset causing
-		// both the send and recv to fail) and we therefore return the
-		// first error and discard the other.
-		return fmt.Errorf("streaming recognition failed: %v", err)
+		return fmt.Errorf("streaming diarization failed: %v", err)
 	default:
 		return nil
 	}
 }
 
-// sendaudio sends audio to a stream.
-func sendaudio(stream juzupb.Juzu_StreamingDiarizeClient,
-	cfg *juzupb.DiarizationConfig, audio io.Reader, bufSize uint32) error {
-
-	// The first message needs to be a config message, and all subsequent
-	// messages must be audio messages.
-
-	// Send the diarization config
-	if err := stream.Send(&juzupb.StreamingDiarizeRequest{
-		Request: &juzupb.StreamingDiarizeRequest_Config{Config: cfg},
-	}); err != nil {
-		// if this failed, we don't need to CloseSend
-		return err
-	}
-
-	// Stream the audio.
+// sendaudio reads audio into a buffer of the given size and writes it to the
+// stream, marking the end of the audio when the reader is exhausted.
+func sendaudio(s *AudioStream, audio io.Reader, bufSize uint32) error {
 	buf := make([]byte, bufSize)
 	for {
 		n, err := audio.Read(buf)
 		if n > 0 {
-			if err2 := stream.Send(&juzupb.StreamingDiarizeRequest{
-				Request: &juzupb.StreamingDiarizeRequest_Audio{
-					Audio: &juzupb.DiarizationAudio{Data: buf[:n]},
-				},
-			}); err2 != nil {
+			if _, err2 := s.Write(buf[:n]); err2 != nil {
 				// if we couldn't Send, the stream has
 				// encountered an error and we don't need to
-				// CloseSend.
+				// end the audio.
 				return err2
 			}
 		}
 
 		if err != nil {
 			// err could be io.EOF, or some other error reading from
-			// audio.  In any case, we need to CloseSend, send the
-			// appropriate error to errCh and return from the function
-			if err2 := stream.CloseSend(); err2 != nil {
+			// audio.  In any case, we need to end the audio and
+			// return the appropriate error.
+			if err2 := s.CloseAudio(); err2 != nil {
 				return err2
 			}
 			if err != io.EOF {
@@ -299,3 +385,95 @@ func sendaudio(stream juzupb.Juzu_StreamingDiarizeClient,
 		}
 	}
 }
+
+// sendOptions holds the settings that control how audio is streamed.
+type sendOptions struct {
+	bufSize  uint32
+	limiters []*rateLimiter
+
+	// If set, audio is buffered so that every message except the last one
+	// holds exactly bufSize bytes.
+	fullChunks bool
+
+	// If non-zero, the audio is raw PCM at this sample rate, and is encoded
+	// to FLAC frames of flacBlockSize samples before being sent.
+	flacSampleRate uint32
+	flacBlockSize  int
+
+	// If non-nil, long silences are removed from the raw PCM audio, at
+	// trimSampleRate, before anything else is done with it.
+	trim           *vad.Config
+	trimSampleRate uint32
+}
+
+// sendOptions works out how audio should be streamed for the given config
+// and call settings.  It returns the config that should be sent to the
+// server, which differs from the given one if the Client changes the encoding
+// of the audio.
+func (c *Client) sendOptions(cfg *juzupb.DiarizationConfig, cc *callConfig) (
+	*juzupb.DiarizationConfig, sendOptions, error) {
+
+	opts := sendOptions{bufSize: c.streamingBufSize}
+	// The limiter of the call is waited on first, so that the slot reserved
+	// on the limiter shared by all calls is not held while the call waits
+	// on its own limiter, slowing down the other calls.
+	if cc.limiter != nil {
+		opts.limiters = append(opts.limiters, cc.limiter)
+	}
+	if c.limiter != nil {
+		opts.limiters = append(opts.limiters, c.limiter)
+	}
+
+	enc := cfg.GetAudioEncoding()
+	if cc.trim != nil {
+		if enc != juzupb.DiarizationConfig_RAW_LINEAR16 || cfg.GetSampleRate() == 0 {
+			return nil, opts, fmt.Errorf("silence trimming requires RAW_LINEAR16 audio with a sample rate")
+		}
+		opts.trim = cc.trim
+		opts.trimSampleRate = cfg.GetSampleRate()
+	}
+
+	// Chunk durations can only be converted to a number of bytes for raw
+	// audio, whose samples are 16-bit mono.  WAV audio may have other
+	// sample formats, given by its header.
+	chunkSamples := 0
+	if c.streamingChunkDuration > 0 && enc == juzupb.DiarizationConfig_RAW_LINEAR16 {
+		if cfg.GetSampleRate() == 0 {
+			return nil, opts, fmt.Errorf("sample rate is required for sizing chunks by duration")
+		}
+
+		chunkSamples = int(uint64(cfg.GetSampleRate()) * uint64(c.streamingChunkDuration) / uint64(time.Second))
+		if chunkSamples < 1 {
+			chunkSamples = 1
+		}
+
+		// Each sample is 16 bits, and sending whole chunks keeps samples
+		// from being split across messages.
+		opts.bufSize = uint32(2 * chunkSamples)
+		opts.fullChunks = true
+	}
+
+	if c.flacEncoding && enc == juzupb.DiarizationConfig_RAW_LINEAR16 {
+		if cfg.GetSampleRate() == 0 {
+			return nil, opts, fmt.Errorf("sample rate is required for FLAC encoding")
+		}
+		opts.flacSampleRate = cfg.GetSampleRate()
+
+		// Each message carries one FLAC frame, so the chunk duration
+		// becomes the frame duration.
+		if chunkSamples > 0 {
+			opts.flacBlockSize = chunkSamples
+			if opts.flacBlockSize < flac.MinBlockSize {
+				opts.flacBlockSize = flac.MinBlockSize
+			}
+			if opts.flacBlockSize > flac.MaxBlockSize {
+				opts.flacBlockSize = flac.MaxBlockSize
+			}
+		}
+
+		cfg = proto.Clone(cfg).(*juzupb.DiarizationConfig)
+		cfg.AudioEncoding = juzupb.DiarizationConfig_FLAC
+	}
+
+	return cfg, opts, nil
+}
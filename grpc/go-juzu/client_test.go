@@ -20,11 +20,16 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
 	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/vad"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes/empty"
 	"google.golang.org/grpc"
@@ -35,6 +40,13 @@ import (
 // functions below.
 type MockJuzuServer struct{}
 
+// receivedAudio records the sizes of the audio messages received by the last
+// call to MockJuzuServer.StreamingDiarize.
+var receivedAudio struct {
+	sync.Mutex
+	sizes []int
+}
+
 // Test Version
 
 var ExpectedVersionResponse = &juzupb.VersionResponse{Juzu: "none", Server: "test"}
@@ -145,6 +157,8 @@ func (s *MockJuzuServer) StreamingDiarize(stream juzupb.Juzu_StreamingDiarizeSer
 
 	// verify that remaining messages are audio messages, and there are at least three of those.
 	count := 0
+	var audio []byte
+	var sizes []int
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
@@ -159,9 +173,38 @@ func (s *MockJuzuServer) StreamingDiarize(stream juzupb.Juzu_StreamingDiarizeSer
 			return fmt.Errorf("streaming diarization failed: all messages after the first should be audio messages")
 		}
 
+		if len(req.GetAudio().GetData()) == 0 {
+			return fmt.Errorf("streaming diarization failed: audio messages should not be empty")
+		}
+
+		audio = append(audio, req.GetAudio().GetData()...)
+		sizes = append(sizes, len(req.GetAudio().GetData()))
 		count++
 	}
 
+	receivedAudio.Lock()
+	receivedAudio.sizes = sizes
+	receivedAudio.Unlock()
+
+	if msg.GetConfig().GetModelId() == "test-flac" {
+		// verify that the audio was encoded by the client
+		if enc := msg.GetConfig().GetAudioEncoding(); enc != juzupb.DiarizationConfig_FLAC {
+			return fmt.Errorf("streaming diarization failed: got %v encoding, want FLAC", enc)
+		}
+		if !bytes.HasPrefix(audio, []byte("fLaC")) {
+			return fmt.Errorf("streaming diarization failed: audio is not a FLAC stream")
+		}
+	}
+
+	if msg.GetConfig().GetModelId() == "test-long" {
+		// diarize the audio for TestDiarizeLongAudio
+		resp, err := mockDiarize(msg.GetConfig(), audio)
+		if err != nil {
+			return fmt.Errorf("streaming diarization failed: %v", err)
+		}
+		return stream.Send(resp)
+	}
+
 	if count < 3 {
 		return fmt.Errorf("streaming diarization failed: expecting at least 3 test audio messages, got %d", count)
 	}
@@ -217,6 +260,119 @@ func TestStreamingDiarize(t *testing.T) {
 
 }
 
+// emptyReader is an io.Reader that returns no data on every other read.
+type emptyReader struct {
+	r     io.Reader
+	empty bool
+}
+
+func (e *emptyReader) Read(p []byte) (int, error) {
+	e.empty = !e.empty
+	if e.empty {
+		return 0, nil
+	}
+	return e.r.Read(p)
+}
+
+// Test that empty audio never reaches the server
+func TestStreamingDiarize_EmptyReads(t *testing.T) {
+	svr, port, err := setupGRPCServer()
+	defer svr.Stop()
+
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+
+	c, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure())
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer c.Close()
+
+	var got *juzupb.DiarizationResponse
+	handleResult := func(resp *juzupb.DiarizationResponse) {
+		got = resp
+	}
+
+	audio := &emptyReader{r: bytes.NewReader(make([]byte, 10*4096))}
+	err = c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{}, audio, handleResult)
+	if err != nil {
+		t.Errorf("did not expect error in streaming diarization; got %v", err)
+	}
+
+	if !proto.Equal(got, ExpectedStreamingDiarizeResponse) {
+		t.Errorf("streaming diarization failed: got %v; want %v", got, ExpectedStreamingDiarizeResponse)
+	}
+}
+
+// Test pushing audio with an AudioStream
+func TestAudioStream(t *testing.T) {
+	svr, port, err := setupGRPCServer()
+	defer svr.Stop()
+
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+
+	c, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure(),
+		juzu.WithStreamingBufferSize(4096))
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer c.Close()
+
+	var mu sync.Mutex
+	var got *juzupb.DiarizationResponse
+	handleResult := func(resp *juzupb.DiarizationResponse) {
+		mu.Lock()
+		got = resp
+		mu.Unlock()
+	}
+
+	s, err := c.NewAudioStream(context.Background(), &juzupb.DiarizationConfig{}, handleResult)
+	if err != nil {
+		t.Fatalf("could not create audio stream: %v", err)
+	}
+
+	// empty writes are ignored, and large writes are split into messages
+	// of the streaming buffer size.
+	for _, n := range []int{0, 2 * 4096, 0, 4096 + 1, 0} {
+		if _, err := s.Write(make([]byte, n)); err != nil {
+			t.Errorf("did not expect error writing %d bytes to audio stream; got %v", n, err)
+		}
+	}
+
+	if err := s.CloseAudio(); err != nil {
+		t.Errorf("did not expect error ending audio; got %v", err)
+	}
+	if err := s.CloseAudio(); err != nil {
+		t.Errorf("did not expect error ending audio a second time; got %v", err)
+	}
+	if _, err := s.Write(make([]byte, 10)); err != io.EOF {
+		t.Errorf("writing after end of audio, want io.EOF, got %v", err)
+	}
+
+	if err := s.Wait(); err != nil {
+		t.Errorf("did not expect error in streaming diarization; got %v", err)
+	}
+	if _, err := s.Write(make([]byte, 10)); err != io.EOF {
+		t.Errorf("writing after end of call, want io.EOF, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !proto.Equal(got, ExpectedStreamingDiarizeResponse) {
+		t.Errorf("streaming diarization failed: got %v; want %v", got, ExpectedStreamingDiarizeResponse)
+	}
+
+	want := []int{4096, 4096, 4096, 1}
+	receivedAudio.Lock()
+	defer receivedAudio.Unlock()
+	if !reflect.DeepEqual(receivedAudio.sizes, want) {
+		t.Errorf("audio stream sent messages of %v bytes; want %v", receivedAudio.sizes, want)
+	}
+}
+
 // Test Streaming Buffer Size Option
 func TestStreamingBufSize(t *testing.T) {
 	svr, port, err := setupGRPCServer()
@@ -239,6 +395,257 @@ func TestStreamingBufSize(t *testing.T) {
 
 }
 
+// Test Streaming Chunk Duration Option
+func TestStreamingChunkDuration(t *testing.T) {
+	svr, port, err := setupGRPCServer()
+	defer svr.Stop()
+
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+
+	_, err = juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure(), juzu.WithStreamingChunkDuration(0))
+	if err == nil {
+		t.Errorf("client creation with streaming chunk duration 0, want failure, got success")
+	}
+
+	c, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure(),
+		juzu.WithStreamingChunkDuration(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer c.Close()
+
+	handleResult := func(resp *juzupb.DiarizationResponse) {}
+
+	err = c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{},
+		bytes.NewReader(make([]byte, 1001)), handleResult)
+	if err == nil {
+		t.Errorf("streaming diarization by chunk duration with no sample rate, want failure, got success")
+	}
+
+	// 10ms at 8kHz is 80 samples or 160 bytes.  Reading one byte at a time
+	// must not lead to smaller messages, or to samples being split.
+	err = c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{SampleRate: 8000},
+		iotest.OneByteReader(bytes.NewReader(make([]byte, 1001))), handleResult)
+	if err != nil {
+		t.Errorf("did not expect error in streaming diarization; got %v", err)
+	}
+
+	want := []int{160, 160, 160, 160, 160, 160, 41}
+	receivedAudio.Lock()
+	got := receivedAudio.sizes
+	receivedAudio.Unlock()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streaming diarization by chunk duration sent messages of %v bytes; want %v", got, want)
+	}
+
+	// The chunk duration does not apply to compressed audio, nor to WAV
+	// audio, whose sample format is in its header.
+	for _, enc := range []juzupb.DiarizationConfig_Encoding{juzupb.DiarizationConfig_MP3, juzupb.DiarizationConfig_WAV} {
+		err = c.StreamingDiarize(context.Background(),
+			&juzupb.DiarizationConfig{AudioEncoding: enc},
+			bytes.NewReader(make([]byte, 10*4096)), handleResult)
+		if err != nil {
+			t.Errorf("did not expect error in streaming diarization of %v audio; got %v", enc, err)
+		}
+	}
+}
+
+// Test FLAC Encoding and Compression Options
+func TestCompression(t *testing.T) {
+	svr, port, err := setupGRPCServer()
+	defer svr.Stop()
+
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+
+	c, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure(),
+		juzu.WithFLACEncoding(), juzu.WithGzipCompression())
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer c.Close()
+
+	var got *juzupb.DiarizationResponse
+	handleResult := func(resp *juzupb.DiarizationResponse) {
+		got = resp
+	}
+
+	audio := make([]byte, 10*4096) // all zeros
+
+	err = c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{ModelId: "test-flac"},
+		bytes.NewReader(audio), handleResult)
+	if err == nil {
+		t.Errorf("streaming diarization with FLAC encoding and no sample rate, want failure, got success")
+	}
+
+	err = c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{ModelId: "test-flac", SampleRate: 16000},
+		bytes.NewReader(audio), handleResult)
+	if err != nil {
+		t.Errorf("did not expect error in streaming diarization with FLAC encoding; got %v", err)
+	}
+
+	if !proto.Equal(got, ExpectedStreamingDiarizeResponse) {
+		t.Errorf("streaming diarization with FLAC encoding failed: got %v; want %v", got, ExpectedStreamingDiarizeResponse)
+	}
+}
+
+// Test Bandwidth Limit Options
+func TestBandwidthLimit(t *testing.T) {
+	svr, port, err := setupGRPCServer()
+	defer svr.Stop()
+
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+
+	_, err = juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure(), juzu.WithBandwidthLimit(0))
+	if err == nil {
+		t.Errorf("client creation with bandwidth limit 0, want failure, got success")
+	}
+
+	// 40960 bytes of audio are sent in 5 messages of 8192 bytes.  The first
+	// message is sent immediately, and each of the others needs to wait for
+	// 8192/81920 = 0.1s at this rate.
+	const rate = 81920
+	const minDuration = 350 * time.Millisecond
+	audio := make([]byte, 10*4096)
+	handleResult := func(resp *juzupb.DiarizationResponse) {}
+
+	c, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure(), juzu.WithBandwidthLimit(rate))
+	if err != nil {
+		t.Fatalf("client creation with bandwidth limit, want success, got %v", err)
+	}
+	defer c.Close()
+
+	start := time.Now()
+	err = c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{}, bytes.NewReader(audio), handleResult)
+	if err != nil {
+		t.Errorf("did not expect error in streaming diarization; got %v", err)
+	}
+	if d := time.Since(start); d < minDuration {
+		t.Errorf("streaming diarization with client bandwidth limit took %v; want at least %v", d, minDuration)
+	}
+
+	// Two concurrent streams share the client's limit, and therefore need
+	// twice as long.
+	start = time.Now()
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{}, bytes.NewReader(audio), handleResult)
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("did not expect error in concurrent streaming diarization; got %v", err)
+		}
+	}
+	if d := time.Since(start); d < 2*minDuration {
+		t.Errorf("concurrent streaming diarization with client bandwidth limit took %v; want at least %v", d, 2*minDuration)
+	}
+
+	// A per-call limit applies to a client without its own limit.
+	c2, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure())
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer c2.Close()
+
+	err = c2.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{}, bytes.NewReader(audio), handleResult,
+		juzu.WithCallBandwidthLimit(0))
+	if err == nil {
+		t.Errorf("streaming diarization with call bandwidth limit 0, want failure, got success")
+	}
+
+	start = time.Now()
+	err = c2.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{}, bytes.NewReader(audio), handleResult,
+		juzu.WithCallBandwidthLimit(rate))
+	if err != nil {
+		t.Errorf("did not expect error in streaming diarization; got %v", err)
+	}
+	if d := time.Since(start); d < minDuration {
+		t.Errorf("streaming diarization with call bandwidth limit took %v; want at least %v", d, minDuration)
+	}
+}
+
+func TestSilenceTrimming(t *testing.T) {
+	svr, port, err := setupGRPCServer()
+	defer svr.Stop()
+
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+
+	c, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure())
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer c.Close()
+
+	// The long audio test samples have 1.5s of silence from 6s, of which all
+	// but the default padding of 12 frames of 20ms on each side is removed.
+	samples := longAudioSamples()
+	cfg := &juzupb.DiarizationConfig{ModelId: "test-long", SampleRate: longAudioRate}
+
+	type segment struct {
+		start, end time.Duration
+		transcript string
+		words      string
+	}
+	segments := func(resp *juzupb.DiarizationResponse) []segment {
+		var segs []segment
+		for _, s := range resp.Results[0].Segments {
+			var words []string
+			for _, w := range s.Words {
+				words = append(words, fmt.Sprintf("%s@%v+%v", w.Word, w.StartTime.AsDuration(), w.Duration.AsDuration()))
+			}
+			segs = append(segs, segment{s.StartTime.AsDuration(), s.EndTime.AsDuration(), s.Transcript,
+				strings.Join(words, " ")})
+		}
+		return segs
+	}
+
+	var want, got []segment
+	err = c.StreamingDiarize(context.Background(), cfg, bytes.NewReader(samples),
+		func(resp *juzupb.DiarizationResponse) { want = segments(resp) })
+	if err != nil {
+		t.Fatalf("did not expect error in streaming diarization; got %v", err)
+	}
+
+	err = c.StreamingDiarize(context.Background(), cfg, bytes.NewReader(samples),
+		func(resp *juzupb.DiarizationResponse) { got = segments(resp) },
+		juzu.WithSilenceTrimming(vad.Config{}))
+	if err != nil {
+		t.Fatalf("did not expect error in streaming diarization with silence trimming; got %v", err)
+	}
+
+	receivedAudio.Lock()
+	sent := 0
+	for _, n := range receivedAudio.sizes {
+		sent += n
+	}
+	receivedAudio.Unlock()
+
+	removed := 2 * int(longAudioRate*(1500-2*240)/1000)
+	if sent != len(samples)-removed {
+		t.Errorf("streaming diarization with silence trimming sent %d bytes; want %d", sent, len(samples)-removed)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("streaming diarization with silence trimming\ngot  %v\nwant %v", got, want)
+	}
+
+	// silence trimming needs raw audio with a known sample rate
+	err = c.StreamingDiarize(context.Background(), &juzupb.DiarizationConfig{ModelId: "test-long"},
+		bytes.NewReader(samples), func(*juzupb.DiarizationResponse) {}, juzu.WithSilenceTrimming(vad.Config{}))
+	if err == nil {
+		t.Errorf("streaming diarization with silence trimming and no sample rate: want error, got nil")
+	}
+}
+
 func TestClient_InvalidURL(t *testing.T) {
 	if _, err := juzu.NewClient(fmt.Sprintf("wrong_localhost:2727"), juzu.WithInsecure(),
 		juzu.WithConnectTimeout(200*time.Millisecond)); err == nil {
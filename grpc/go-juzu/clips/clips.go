@@ -0,0 +1,259 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clips cuts the audio of each speaker out of diarized audio, such as
+// to enroll their voice or to review the diarization.
+//
+// Each speaker gets either a single clip joining all their segments, or a
+// clip per segment.  Clips are written as WAV files in the format of the
+// source audio.
+package clips
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Source is 16-bit linear PCM audio to cut clips from.
+type Source struct {
+	r      io.ReaderAt
+	format audio.Format
+	offset int64 // offset of the samples
+	size   int64 // size of the samples
+}
+
+// NewPCMSource returns a source of raw audio of the given format and size.
+func NewPCMSource(r io.ReaderAt, size int64, format audio.Format) (*Source, error) {
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return nil, fmt.Errorf("invalid audio format %+v", format)
+	}
+	return &Source{r: r, format: format, size: size}, nil
+}
+
+// NewWAVSource returns a source of the WAV file of the given size.
+func NewWAVSource(r io.ReaderAt, size int64) (*Source, error) {
+	h, err := audio.ReadWAVHeader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	if h.BitsPerSample != 16 {
+		return nil, fmt.Errorf("unsupported WAV sample size of %d bits; only 16 bits are supported", h.BitsPerSample)
+	}
+	if h.SampleRate <= 0 || h.Channels <= 0 {
+		return nil, fmt.Errorf("invalid audio format %+v", h.Format())
+	}
+
+	src := &Source{r: r, format: h.Format(), offset: h.DataOffset, size: size - h.DataOffset}
+	if h.DataSize < src.size {
+		src.size = h.DataSize
+	}
+	return src, nil
+}
+
+// Duration returns the duration of the audio.
+func (s *Source) Duration() time.Duration {
+	return s.format.Duration(s.size)
+}
+
+// Range is a time range of the source audio.
+type Range struct {
+	Start, End time.Duration
+}
+
+// Clip is audio of one speaker, made of ranges of the source audio.
+type Clip struct {
+	Speaker string
+
+	// Index of the clip among those of the speaker, in order.
+	Index int
+
+	// Ranges holds the parts of the source audio joined in the clip, in
+	// order.
+	Ranges []Range
+}
+
+// Duration returns the duration of the clip.
+func (c *Clip) Duration() time.Duration {
+	var d time.Duration
+	for _, r := range c.Ranges {
+		d += r.End - r.Start
+	}
+	return d
+}
+
+// Options configures how clips are cut.
+type Options struct {
+	// Padding added before and after each segment, within the audio.
+	Padding time.Duration
+
+	// MinDuration is the duration below which segments are left out, as
+	// short segments often hold more noise or crosstalk than speech.
+	MinDuration time.Duration
+
+	// PerSegment cuts a clip for each segment, instead of a single clip per
+	// speaker.
+	PerSegment bool
+}
+
+// Plan returns the clips of the segments of the result, for audio of the
+// given duration, by speaker in order of first appearance.  Without
+// PerSegment, padded segments of a speaker that overlap are joined so that no
+// audio is repeated.
+func Plan(r *juzupb.DiarizationResult, total time.Duration, opts Options) []*Clip {
+	segs := append([]*juzupb.Segment(nil), r.Segments...)
+	sort.SliceStable(segs, func(i, j int) bool {
+		return segs[i].StartTime.AsDuration() < segs[j].StartTime.AsDuration()
+	})
+
+	var clips []*Clip
+	bySpeaker := make(map[string]*Clip)
+	counts := make(map[string]int)
+	for _, s := range segs {
+		start, end := s.StartTime.AsDuration(), s.EndTime.AsDuration()
+		if end-start < opts.MinDuration || end <= start {
+			continue
+		}
+		start -= opts.Padding
+		if start < 0 {
+			start = 0
+		}
+		end += opts.Padding
+		if end > total {
+			end = total
+		}
+		if end <= start {
+			continue
+		}
+
+		if opts.PerSegment {
+			clips = append(clips, &Clip{Speaker: s.SpeakerLabel, Index: counts[s.SpeakerLabel], Ranges: []Range{{start, end}}})
+			counts[s.SpeakerLabel]++
+			continue
+		}
+
+		c := bySpeaker[s.SpeakerLabel]
+		if c == nil {
+			c = &Clip{Speaker: s.SpeakerLabel}
+			bySpeaker[s.SpeakerLabel] = c
+			clips = append(clips, c)
+		}
+		if n := len(c.Ranges); n > 0 && start <= c.Ranges[n-1].End {
+			if end > c.Ranges[n-1].End {
+				c.Ranges[n-1].End = end
+			}
+			continue
+		}
+		c.Ranges = append(c.Ranges, Range{start, end})
+	}
+	return clips
+}
+
+// WriteWAV writes the audio of the clip to w as a WAV file.
+func (s *Source) WriteWAV(w io.Writer, c *Clip) error {
+	type part struct{ start, end int64 }
+	var parts []part
+	var size int64
+	for _, r := range c.Ranges {
+		start, end := s.format.Offset(r.Start), s.format.Offset(r.End)
+		if end > s.size {
+			end = s.size
+		}
+		if end > start {
+			parts = append(parts, part{start, end})
+			size += end - start
+		}
+	}
+
+	h := audio.WAVHeader{SampleRate: s.format.SampleRate, Channels: s.format.Channels, BitsPerSample: 16, DataSize: size}
+	if _, err := w.Write(h.Bytes()); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := io.Copy(w, io.NewSectionReader(s.r, s.offset+p.start, p.end-p.start)); err != nil {
+			return fmt.Errorf("unable to copy audio: %v", err)
+		}
+	}
+	return nil
+}
+
+// WriteDir cuts the clips of the result and writes them as WAV files in dir,
+// which must exist.  Clips are named after their speaker, as in "1.wav", with
+// the index of the clip added with PerSegment, as in "1-003.wav".  Characters
+// of speaker labels that are not safe in file names are replaced by
+// underscores, and a number is added to the names of speakers whose labels
+// become the same as another's, as in "a_b_2.wav", so that no clip overwrites
+// another.  Files already in dir are never replaced: WriteDir fails instead.
+// The paths of the files written are returned in the order of Plan.
+func WriteDir(dir string, src *Source, r *juzupb.DiarizationResult, opts Options) ([]string, error) {
+	names := make(map[string]string) // file name of each speaker
+	taken := make(map[string]bool)
+	speakerName := func(label string) string {
+		if name, ok := names[label]; ok {
+			return name
+		}
+		name := fileName(label)
+		for i := 2; taken[name]; i++ {
+			name = fmt.Sprintf("%s_%d", fileName(label), i)
+		}
+		taken[name] = true
+		names[label] = name
+		return name
+	}
+
+	var paths []string
+	for _, c := range Plan(r, src.Duration(), opts) {
+		name := speakerName(c.Speaker)
+		if opts.PerSegment {
+			name = fmt.Sprintf("%s-%03d", name, c.Index)
+		}
+		path := filepath.Join(dir, name+".wav")
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if err != nil {
+			return paths, fmt.Errorf("unable to create clip: %v", err)
+		}
+		err = src.WriteWAV(f, c)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return paths, fmt.Errorf("unable to write clip %s: %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// fileName returns the speaker label made safe to use as a file name.
+func fileName(label string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, label)
+	if name == "" {
+		return "_"
+	}
+	return name
+}
@@ -0,0 +1,180 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clips_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/clips"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func segment(label string, start, end time.Duration) *juzupb.Segment {
+	return &juzupb.Segment{SpeakerLabel: label, StartTime: durationpb.New(start), EndTime: durationpb.New(end)}
+}
+
+func result() *juzupb.DiarizationResult {
+	ms := time.Millisecond
+	return &juzupb.DiarizationResult{Segments: []*juzupb.Segment{
+		segment("A", 0, 1000*ms),
+		segment("B", 1000*ms, 2000*ms),
+		segment("A", 1900*ms, 3000*ms),
+		segment("A", 2900*ms, 3300*ms),
+		segment("A", 3500*ms, 3600*ms),
+		segment("c/d", 3700*ms, 4000*ms),
+	}}
+}
+
+// source returns 4s of audio at 8kHz, each sample holding its time in ms.
+func source() []byte {
+	h := audio.WAVHeader{SampleRate: 8000, Channels: 1, BitsPerSample: 16, DataSize: 64000}
+	b := h.Bytes()
+	for i := 0; i < 32000; i++ {
+		b = append(b, 0, 0)
+		binary.LittleEndian.PutUint16(b[len(b)-2:], uint16(i/8))
+	}
+	return b
+}
+
+func TestPlan(t *testing.T) {
+	ms := time.Millisecond
+	opts := clips.Options{Padding: 100 * ms, MinDuration: 200 * ms}
+
+	got := clips.Plan(result(), 3900*ms, opts)
+	want := []*clips.Clip{
+		{Speaker: "A", Ranges: []clips.Range{{0, 1100 * ms}, {1800 * ms, 3400 * ms}}},
+		{Speaker: "B", Ranges: []clips.Range{{900 * ms, 2100 * ms}}},
+		{Speaker: "c/d", Ranges: []clips.Range{{3600 * ms, 3900 * ms}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan = %+v; want %+v", got, want)
+	}
+	if d := got[0].Duration(); d != 2700*ms {
+		t.Errorf("duration of clip is %v; want 2.7s", d)
+	}
+
+	opts.PerSegment = true
+	got = clips.Plan(result(), 3900*ms, opts)
+	var desc []clips.Clip
+	for _, c := range got {
+		desc = append(desc, *c)
+	}
+	wantSegs := []clips.Clip{
+		{Speaker: "A", Index: 0, Ranges: []clips.Range{{0, 1100 * ms}}},
+		{Speaker: "B", Index: 0, Ranges: []clips.Range{{900 * ms, 2100 * ms}}},
+		{Speaker: "A", Index: 1, Ranges: []clips.Range{{1800 * ms, 3100 * ms}}},
+		{Speaker: "A", Index: 2, Ranges: []clips.Range{{2800 * ms, 3400 * ms}}},
+		{Speaker: "c/d", Index: 0, Ranges: []clips.Range{{3600 * ms, 3900 * ms}}},
+	}
+	if !reflect.DeepEqual(desc, wantSegs) {
+		t.Errorf("Plan per segment = %+v; want %+v", desc, wantSegs)
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	b := source()
+	src, err := clips.NewWAVSource(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "clips")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	paths, err := clips.WriteDir(dir, src, result(), clips.Options{Padding: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "A.wav"), filepath.Join(dir, "B.wav"), filepath.Join(dir, "c_d.wav")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths are %q; want %q", paths, want)
+	}
+
+	// labels that make the same file name get distinct files
+	r := &juzupb.DiarizationResult{Segments: []*juzupb.Segment{
+		segment("c d", 0, time.Second),
+		segment("c/d", time.Second, 2*time.Second),
+		segment("c_d", 2*time.Second, 3*time.Second),
+	}}
+	dupDir := filepath.Join(dir, "dup")
+	if err := os.Mkdir(dupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	dup, err := clips.WriteDir(dupDir, src, r, clips.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{filepath.Join(dupDir, "c_d.wav"), filepath.Join(dupDir, "c_d_2.wav"), filepath.Join(dupDir, "c_d_3.wav")}
+	if !reflect.DeepEqual(dup, want) {
+		t.Errorf("paths are %q; want %q", dup, want)
+	}
+
+	// existing files are not replaced
+	if _, err := clips.WriteDir(dupDir, src, r, clips.Options{}); err == nil {
+		t.Errorf("writing clips over existing files: want error, got nil")
+	}
+
+	// A: 0-1.1s and 1.8-3.7s
+	f, err := ioutil.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := audio.ReadWAVHeader(bytes.NewReader(f))
+	if err != nil || h.SampleRate != 8000 || h.Channels != 1 || h.DataSize != 2*8000*3 || int64(len(f)) != 44+h.DataSize {
+		t.Fatalf("clip has header %+v (%v) and %d bytes", h, err, len(f))
+	}
+	for _, c := range []struct{ sample, ms int }{{0, 0}, {8799, 1099}, {8800, 1800}, {23999, 3699}} {
+		if got := int(binary.LittleEndian.Uint16(f[44+2*c.sample:])); got != c.ms {
+			t.Errorf("sample %d of clip is from %dms; want %dms", c.sample, got, c.ms)
+		}
+	}
+
+	// raw audio of the same samples
+	src, err = clips.NewPCMSource(bytes.NewReader(b[44:]), int64(len(b)-44), audio.Format{SampleRate: 8000, Channels: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := src.WriteWAV(&buf, &clips.Clip{Ranges: []clips.Range{{3900 * time.Millisecond, 5 * time.Second}}}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 44+1600 || binary.LittleEndian.Uint16(buf.Bytes()[44:]) != 3900 {
+		t.Errorf("clip at the end of raw audio has %d bytes", buf.Len())
+	}
+}
+
+func TestNewWAVSourceInvalid(t *testing.T) {
+	for _, h := range []audio.WAVHeader{
+		{SampleRate: 8000, BitsPerSample: 16, DataSize: 64000},
+		{Channels: 1, BitsPerSample: 16, DataSize: 64000},
+	} {
+		b := append(h.Bytes(), make([]byte, 64000)...)
+		if _, err := clips.NewWAVSource(bytes.NewReader(b), int64(len(b))); err == nil {
+			t.Errorf("WAV of %d Hz and %d channels: want error, got nil", h.SampleRate, h.Channels)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+module github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/cmd
+
+go 1.13
+
+require (
+	github.com/cobaltspeech/sdk-juzu/grpc/go-juzu v0.10.0
+	github.com/fsnotify/fsnotify v1.5.1
+	google.golang.org/grpc v1.42.0
+	google.golang.org/protobuf v1.27.1
+)
+
+replace github.com/cobaltspeech/sdk-juzu/grpc/go-juzu => ../
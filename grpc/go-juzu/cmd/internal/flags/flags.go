@@ -0,0 +1,125 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flags defines the command line flags shared by the commands, for
+// connecting to juzu server and configuring diarization.
+package flags
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Client holds the flags used to connect to juzu server.
+type Client struct {
+	Addr       string
+	Insecure   bool
+	CACert     string
+	ClientCert string
+	ClientKey  string
+	Timeout    time.Duration
+}
+
+// Register defines the client flags in fs.
+func (c *Client) Register(fs *flag.FlagSet) {
+	fs.StringVar(&c.Addr, "server", "localhost:2727", "address (host:port) of juzu server")
+	fs.BoolVar(&c.Insecure, "insecure", false, "connect without TLS")
+	fs.StringVar(&c.CACert, "ca-cert", "", "PEM file of the CA certificate used to verify the server")
+	fs.StringVar(&c.ClientCert, "client-cert", "", "PEM file of the client certificate, for mutual TLS")
+	fs.StringVar(&c.ClientKey, "client-key", "", "PEM file of the client key, for mutual TLS")
+	fs.DurationVar(&c.Timeout, "connect-timeout", 10*time.Second, "timeout for connecting to the server")
+}
+
+// Dial connects to the server as set up by the flags.  Any further options
+// are applied after those set by the flags.
+func (c *Client) Dial(opts ...juzu.Option) (*juzu.Client, error) {
+	copts := []juzu.Option{juzu.WithConnectTimeout(c.Timeout)}
+
+	if c.Insecure {
+		copts = append(copts, juzu.WithInsecure())
+	}
+	if c.CACert != "" {
+		cert, err := ioutil.ReadFile(c.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA certificate: %v", err)
+		}
+		copts = append(copts, juzu.WithServerCert(cert))
+	}
+	if c.ClientCert != "" || c.ClientKey != "" {
+		cert, err := ioutil.ReadFile(c.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client certificate: %v", err)
+		}
+		key, err := ioutil.ReadFile(c.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client key: %v", err)
+		}
+		copts = append(copts, juzu.WithClientCert(cert, key))
+	}
+
+	return juzu.NewClient(c.Addr, append(copts, opts...)...)
+}
+
+// Config holds the flags that set the fields of a DiarizationConfig.
+type Config struct {
+	ModelID             string
+	NumSpeakers         uint
+	SampleRate          uint
+	Encoding            string
+	CubicModelID        string
+	EnableRawTranscript bool
+}
+
+// Register defines the config flags in fs, with the given default audio
+// encoding.
+func (c *Config) Register(fs *flag.FlagSet, encoding string) {
+	var encodings []string
+	for i := 0; i < len(juzupb.DiarizationConfig_Encoding_name); i++ {
+		encodings = append(encodings, strings.ToLower(juzupb.DiarizationConfig_Encoding_name[int32(i)]))
+	}
+
+	fs.StringVar(&c.ModelID, "model", "", "ID of the diarization model (required)")
+	fs.UintVar(&c.NumSpeakers, "speakers", 0, "number of speakers expected in the audio; 0 if unknown")
+	fs.UintVar(&c.SampleRate, "sample-rate", 0, "sample rate of the audio")
+	fs.StringVar(&c.Encoding, "encoding", encoding,
+		fmt.Sprintf("encoding of the audio (%s)", strings.Join(encodings, ", ")))
+	fs.StringVar(&c.CubicModelID, "cubic-model", "", "ID of the cubic model used for transcription, if any")
+	fs.BoolVar(&c.EnableRawTranscript, "raw-transcript", false, "include raw transcripts in the results")
+}
+
+// DiarizationConfig returns the config set by the flags.
+func (c *Config) DiarizationConfig() (*juzupb.DiarizationConfig, error) {
+	if c.ModelID == "" {
+		return nil, fmt.Errorf("a model ID is required")
+	}
+	enc, ok := juzupb.DiarizationConfig_Encoding_value[strings.ToUpper(c.Encoding)]
+	if !ok {
+		return nil, fmt.Errorf("unknown audio encoding %q", c.Encoding)
+	}
+
+	return &juzupb.DiarizationConfig{
+		ModelId:             c.ModelID,
+		NumSpeakers:         uint32(c.NumSpeakers),
+		SampleRate:          uint32(c.SampleRate),
+		AudioEncoding:       juzupb.DiarizationConfig_Encoding(enc),
+		CubicModelId:        c.CubicModelID,
+		EnableRawTranscript: c.EnableRawTranscript,
+	}, nil
+}
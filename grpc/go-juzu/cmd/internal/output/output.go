@@ -0,0 +1,191 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package output writes diarization results in the formats offered by the
+// commands.
+package output
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/eaf"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/rttm"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/subtitle"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/tabular"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/textgrid"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Format is an output format of diarization results.
+type Format struct {
+	Name string
+
+	// Extension of files in the format, including the leading dot.
+	Ext string
+
+	// Write writes the results, for audio of the given name, to w.
+	Write func(w io.Writer, name string, results []*juzupb.DiarizationResult) error
+}
+
+var formats = map[string]Format{}
+
+func register(f Format) {
+	formats[f.Name] = f
+}
+
+func init() {
+	register(Format{Name: "json", Ext: ".json", Write: writeJSON})
+	register(Format{Name: "text", Ext: ".txt", Write: writeText})
+	register(Format{Name: "rttm", Ext: ".rttm", Write: writeRTTM})
+	register(Format{Name: "srt", Ext: ".srt", Write: writeSubtitles(subtitle.WriteSRT)})
+	register(Format{Name: "vtt", Ext: ".vtt", Write: writeSubtitles(subtitle.WriteVTT)})
+	register(Format{Name: "csv", Ext: ".csv", Write: writeTabular(tabular.NewCSVWriter, tabular.Segments)})
+	register(Format{Name: "csv-words", Ext: ".words.csv", Write: writeTabular(tabular.NewCSVWriter, tabular.Words)})
+	register(Format{Name: "jsonl", Ext: ".jsonl", Write: writeTabular(tabular.NewJSONLinesWriter, tabular.Segments)})
+	register(Format{Name: "jsonl-words", Ext: ".words.jsonl", Write: writeTabular(tabular.NewJSONLinesWriter, tabular.Words)})
+	register(Format{Name: "textgrid", Ext: ".TextGrid", Write: writeTextGrid})
+	register(Format{Name: "eaf", Ext: ".eaf", Write: writeEAF})
+}
+
+// Lookup returns the format of the given name.
+func Lookup(name string) (Format, error) {
+	f, ok := formats[name]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown output format %q; valid formats are %s",
+			name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Names returns the names of all formats, sorted.
+func Names() []string {
+	var names []string
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FinalResults returns the results of the responses that are not partial.
+func FinalResults(responses []*juzupb.DiarizationResponse) []*juzupb.DiarizationResult {
+	var results []*juzupb.DiarizationResult
+	for _, resp := range responses {
+		for _, r := range resp.Results {
+			if !r.IsPartial {
+				results = append(results, r)
+			}
+		}
+	}
+	return results
+}
+
+// writeJSON writes the results as a DiarizationResponse in protobuf JSON.
+func writeJSON(w io.Writer, name string, results []*juzupb.DiarizationResult) error {
+	b, err := protojson.MarshalOptions{Multiline: true}.Marshal(&juzupb.DiarizationResponse{Results: results})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// writeText writes one line per segment, with its times, speaker and
+// transcript.
+func writeText(w io.Writer, name string, results []*juzupb.DiarizationResult) error {
+	for _, r := range results {
+		for _, s := range r.Segments {
+			if _, err := fmt.Fprintf(w, "[%s - %s] %s: %s\n", formatTime(s.StartTime.AsDuration()),
+				formatTime(s.EndTime.AsDuration()), s.SpeakerLabel, s.Transcript); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeRTTM writes the segments as RTTM, with the name of the audio without
+// its extension as file ID.
+func writeRTTM(w io.Writer, name string, results []*juzupb.DiarizationResult) error {
+	id := fileID(name)
+	for _, r := range results {
+		if err := rttm.Write(w, id, 1, r.Segments); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSubtitles returns a function that writes the segments of all results
+// with the given subtitle writer.
+func writeSubtitles(write func(io.Writer, []*juzupb.Segment, subtitle.Options) error) func(
+	io.Writer, string, []*juzupb.DiarizationResult) error {
+
+	return func(w io.Writer, name string, results []*juzupb.DiarizationResult) error {
+		return write(w, segments(results), subtitle.Options{})
+	}
+}
+
+// writeTabular returns a function that writes rows of the given level with
+// the given tabular writer, with the name of the audio without its extension
+// as ID.
+func writeTabular(newWriter func(io.Writer, tabular.Level) *tabular.Writer, level tabular.Level) func(
+	io.Writer, string, []*juzupb.DiarizationResult) error {
+
+	return func(w io.Writer, name string, results []*juzupb.DiarizationResult) error {
+		return newWriter(w, level).Write(fileID(name), results)
+	}
+}
+
+// writeTextGrid writes the segments as a Praat TextGrid, ending with the last
+// segment.
+func writeTextGrid(w io.Writer, name string, results []*juzupb.DiarizationResult) error {
+	return textgrid.Write(w, segments(results), 0)
+}
+
+// writeEAF writes the segments as an ELAN document linked to the audio.
+func writeEAF(w io.Writer, name string, results []*juzupb.DiarizationResult) error {
+	var opts eaf.Options
+	if path, err := filepath.Abs(name); err == nil {
+		opts.MediaURL = (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	}
+	return eaf.Write(w, segments(results), opts)
+}
+
+// segments returns the segments of all results.
+func segments(results []*juzupb.DiarizationResult) []*juzupb.Segment {
+	var segs []*juzupb.Segment
+	for _, r := range results {
+		segs = append(segs, r.Segments...)
+	}
+	return segs
+}
+
+// fileID returns the name of the audio without its directory and extension.
+func fileID(name string) string {
+	return strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+}
+
+// formatTime formats t as hours, minutes, seconds and milliseconds.
+func formatTime(t time.Duration) string {
+	ms := t.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,138 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command juzu-watch watches a directory for new audio files, and diarizes
+// each of them with juzu server once it has stopped changing.
+//
+// The result of each file is written next to it in the chosen format, and the
+// file is then moved to the done directory.  Files that could not be diarized
+// are moved to the failed directory instead, and a .error file holding the
+// reason is written in their place.  Results never overwrite existing files:
+// a result whose name is taken gets a numeric suffix, as does a file moved to
+// a directory that already holds one of its name.  Files with the extension of
+// results are never diarized.  Files left in the directory when the command
+// is stopped are diarized when it is started again.
+//
+// Usage:
+//
+//	juzu-watch -dir incoming -model 1 [flags]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/batch"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/cmd/internal/flags"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/cmd/internal/output"
+)
+
+func main() {
+	var (
+		client   flags.Client
+		config   flags.Config
+		w        watcher
+		exts     string
+		format   string
+		attempts int
+	)
+
+	fs := flag.NewFlagSet("juzu-watch", flag.ExitOnError)
+	client.Register(fs)
+	config.Register(fs, "wav")
+	fs.StringVar(&w.dir, "dir", "", "directory to watch (required)")
+	fs.StringVar(&w.doneDir, "done", "", "directory for diarized files (default <dir>/done)")
+	fs.StringVar(&w.failedDir, "failed", "", "directory for files that could not be diarized (default <dir>/failed)")
+	fs.StringVar(&exts, "ext", ".wav", "comma separated extensions of the files to diarize; all files if empty")
+	fs.DurationVar(&w.stable, "stable", 2*time.Second, "time a file must stop changing for before it is diarized")
+	fs.DurationVar(&w.poll, "poll", 0, "poll the directory at this interval instead of watching it for changes")
+	fs.IntVar(&w.concurrency, "concurrency", batch.DefaultConcurrency, "maximum number of files diarized at a time")
+	fs.IntVar(&attempts, "attempts", batch.DefaultAttempts, "maximum number of attempts at diarizing each file")
+	fs.StringVar(&format, "format", "json",
+		fmt.Sprintf("format of the results (%s)", strings.Join(output.Names(), ", ")))
+	_ = fs.Parse(os.Args[1:])
+
+	w.log = log.New(os.Stderr, "", log.LstdFlags)
+	if err := setup(&w, &client, &config, exts, format, attempts); err != nil {
+		w.log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		w.log.Printf("stopping")
+		cancel()
+	}()
+
+	if err := w.run(ctx); err != nil {
+		w.log.Fatal(err)
+	}
+}
+
+// setup completes the watcher from the flags, and connects to the server.
+func setup(w *watcher, client *flags.Client, config *flags.Config, exts, format string, attempts int) error {
+	if w.dir == "" {
+		return fmt.Errorf("a directory to watch is required")
+	}
+	if w.doneDir == "" {
+		w.doneDir = filepath.Join(w.dir, "done")
+	}
+	if w.failedDir == "" {
+		w.failedDir = filepath.Join(w.dir, "failed")
+	}
+	if w.concurrency < 1 {
+		return fmt.Errorf("invalid concurrency %d", w.concurrency)
+	}
+	for _, ext := range strings.Split(exts, ",") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext != "" {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			w.exts = append(w.exts, ext)
+		}
+	}
+
+	var err error
+	if w.format, err = output.Lookup(format); err != nil {
+		return err
+	}
+	for _, ext := range w.exts {
+		// results are written in the watched directory, and must not
+		// be diarized in turn
+		if w.isResult(ext) {
+			return fmt.Errorf("files with extension %s cannot be diarized, as results of format %s have it",
+				ext, w.format.Name)
+		}
+	}
+	if w.cfg, err = config.DiarizationConfig(); err != nil {
+		return err
+	}
+
+	c, err := client.Dial()
+	if err != nil {
+		return fmt.Errorf("unable to connect to %s: %v", client.Addr, err)
+	}
+	w.runner = &batch.Runner{Client: c, Attempts: attempts}
+	return nil
+}
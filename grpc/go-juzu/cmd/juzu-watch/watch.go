@@ -0,0 +1,350 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/batch"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/cmd/internal/output"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watcher watches a directory for new audio files, and diarizes each file
+// once it has stopped changing.
+type watcher struct {
+	dir       string
+	doneDir   string
+	failedDir string
+
+	// extensions of the files to diarize, lower case with the leading dot;
+	// all files are diarized if empty
+	exts []string
+
+	// a file is stable once its size and modification time have not
+	// changed for this long
+	stable time.Duration
+
+	// if non-zero, the directory is polled at this interval instead of
+	// being watched for changes
+	poll time.Duration
+
+	concurrency int
+	cfg         *juzupb.DiarizationConfig
+	format      output.Format
+	runner      *batch.Runner
+	log         *log.Logger
+
+	mu      sync.Mutex
+	pending map[string]fileState // files waiting to become stable
+	active  map[string]bool      // files being diarized
+
+	files sync.Mutex // held while naming and creating result and moved files
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+	since   time.Time // when size and modTime were last seen changing
+}
+
+// run watches the directory until the context is cancelled, and then waits
+// for the files being diarized.
+func (w *watcher) run(ctx context.Context) error {
+	for _, dir := range []string{w.doneDir, w.failedDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	w.pending = make(map[string]fileState)
+	w.active = make(map[string]bool)
+
+	var events chan fsnotify.Event
+	var errs chan error
+	if w.poll == 0 {
+		fw, err := fsnotify.NewWatcher()
+		if err == nil {
+			err = fw.Add(w.dir)
+			if err != nil {
+				_ = fw.Close()
+			}
+		}
+		if err != nil {
+			w.log.Printf("unable to watch %s, polling it instead: %v", w.dir, err)
+			w.poll = time.Second
+		} else {
+			defer fw.Close()
+			events, errs = fw.Events, fw.Errors
+		}
+	}
+
+	// Files already in the directory are found by a first scan, and by
+	// further scans when polling.
+	if err := w.scan(); err != nil {
+		return err
+	}
+	var scans <-chan time.Time
+	if w.poll > 0 {
+		t := time.NewTicker(w.poll)
+		defer t.Stop()
+		scans = t.C
+	}
+
+	check := w.stable / 4
+	if check < 10*time.Millisecond {
+		check = 10 * time.Millisecond
+	}
+	checks := time.NewTicker(check)
+	defer checks.Stop()
+
+	sem := make(chan struct{}, w.concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case ev := <-events:
+			if ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename) != 0 {
+				w.seen(ev.Name)
+			}
+
+		case err := <-errs:
+			w.log.Printf("error watching %s: %v", w.dir, err)
+
+		case <-scans:
+			if err := w.scan(); err != nil {
+				w.log.Printf("unable to scan %s: %v", w.dir, err)
+			}
+
+		case now := <-checks.C:
+			for _, path := range w.stableFiles(now) {
+				path := path
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					select {
+					case sem <- struct{}{}:
+					case <-ctx.Done():
+						return
+					}
+					w.process(ctx, path)
+					<-sem
+				}()
+			}
+		}
+	}
+}
+
+// scan marks all files in the directory as seen.
+func (w *watcher) scan() error {
+	entries, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Mode().IsRegular() {
+			w.seen(filepath.Join(w.dir, e.Name()))
+		}
+	}
+	return nil
+}
+
+// seen adds the file at path to the pending files, unless it is not audio or
+// is already known.
+func (w *watcher) seen(path string) {
+	name := filepath.Base(path)
+	if strings.HasPrefix(name, ".") || !w.accepts(name) || w.isResult(name) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.pending[path]; ok || w.active[path] {
+		return
+	}
+	w.pending[path] = fileState{since: time.Now()}
+}
+
+func (w *watcher) accepts(name string) bool {
+	if len(w.exts) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range w.exts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// stableFiles updates the state of the pending files, and returns those that
+// have become stable, which become active.
+func (w *watcher) stableFiles(now time.Time) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var stable []string
+	for path, st := range w.pending {
+		fi, err := os.Stat(path)
+		if err != nil {
+			// removed or renamed before becoming stable
+			delete(w.pending, path)
+			continue
+		}
+
+		if fi.Size() != st.size || !fi.ModTime().Equal(st.modTime) {
+			w.pending[path] = fileState{size: fi.Size(), modTime: fi.ModTime(), since: now}
+			continue
+		}
+		if now.Sub(st.since) >= w.stable {
+			delete(w.pending, path)
+			w.active[path] = true
+			stable = append(stable, path)
+		}
+	}
+	return stable
+}
+
+// process diarizes the file at path, writes the result, or the error, next to
+// it and moves the file to the done, or failed, directory.
+func (w *watcher) process(ctx context.Context, path string) {
+	// However processing ends, the file is no longer active, so that it is
+	// diarized again if it is seen again.
+	defer func() {
+		w.mu.Lock()
+		delete(w.active, path)
+		w.mu.Unlock()
+	}()
+
+	results, err := w.runner.Run(ctx, []batch.Job{{Path: path, Config: w.cfg}})
+	if err == nil {
+		err = results[0].Err
+	}
+	if ctx.Err() != nil {
+		// Shutting down; the file is diarized again on the next start.
+		w.log.Printf("%s: interrupted", path)
+		return
+	}
+
+	name := filepath.Base(path)
+	dir, out, ext := w.doneDir, &bytes.Buffer{}, w.format.Ext
+	if err == nil {
+		err = w.format.Write(out, name, output.FinalResults(results[0].Responses))
+	}
+	if err != nil {
+		w.log.Printf("%s: diarization failed: %v", path, err)
+		dir, ext = w.failedDir, errorExt
+		out.Reset()
+		fmt.Fprintln(out, err)
+	}
+
+	// The result is written before the audio is moved, so that a crash in
+	// between leaves the audio to be diarized again.  If the result cannot
+	// be written, the audio is moved to the failed directory rather than
+	// being left to fail again.
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	result, err := w.writeResult(filepath.Dir(path), base, ext, out.Bytes())
+	if err != nil {
+		w.log.Printf("%s: unable to write result: %v", path, err)
+		dir = w.failedDir
+	}
+	if err := w.move(path, dir); err != nil {
+		// The file is left in the directory, and diarized again when
+		// it is next seen; its result is removed so that it is not
+		// written twice.
+		w.log.Printf("%s: unable to move file to %s: %v", path, dir, err)
+		if result != "" {
+			if err := os.Remove(result); err != nil {
+				w.log.Printf("%s: unable to remove result: %v", path, err)
+			}
+		}
+		return
+	}
+
+	if dir == w.doneDir {
+		w.log.Printf("%s: done", path)
+	}
+}
+
+// errorExt is the extension of the files holding the reason a file could not
+// be diarized.
+const errorExt = ".error"
+
+// isResult tells whether the file of the given name has the extension of
+// results, which are written in the watched directory but never diarized.
+func (w *watcher) isResult(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, strings.ToLower(w.format.Ext)) || strings.HasSuffix(name, errorExt)
+}
+
+// writeResult writes data to a new file in dir named base+ext, or, if that
+// name is taken, base_2+ext, base_3+ext and so on, and returns its path.
+func (w *watcher) writeResult(dir, base, ext string, data []byte) (string, error) {
+	// Files are named one at a time, so that two files diarized at the
+	// same time never take the same name.
+	w.files.Lock()
+	defer w.files.Unlock()
+	path := uniquePath(dir, base, ext)
+	if err := writeFile(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// move moves the file at path to dir, under a new name if its name is taken.
+func (w *watcher) move(path, dir string) error {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+
+	w.files.Lock()
+	defer w.files.Unlock()
+	return os.Rename(path, uniquePath(dir, strings.TrimSuffix(name, ext), ext))
+}
+
+// uniquePath returns the path in dir of the file named base+ext, or, if that
+// file exists, of the first of base_2+ext, base_3+ext and so on that does not.
+func uniquePath(dir, base, ext string) string {
+	path := filepath.Join(dir, base+ext)
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s_%d%s", base, i, ext))
+	}
+}
+
+// writeFile writes data to the file at path, through a temporary file so that
+// the file never holds partial data.
+func writeFile(path string, data []byte) error {
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,255 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/batch"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/cmd/internal/output"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// mockDiarizer returns the audio as the transcript of a single segment, or
+// fails if the audio is "bad".
+type mockDiarizer struct{}
+
+func (mockDiarizer) StreamingDiarize(ctx context.Context, cfg *juzupb.DiarizationConfig, audio io.Reader,
+	handlerFunc juzu.DiarizationResponseHandler, opts ...juzu.CallOption) error {
+
+	b, err := ioutil.ReadAll(audio)
+	if err != nil {
+		return err
+	}
+	if string(b) == "bad" {
+		return fmt.Errorf("streaming diarization failed: bad audio")
+	}
+	handlerFunc(&juzupb.DiarizationResponse{Results: []*juzupb.DiarizationResult{{
+		Segments: []*juzupb.Segment{{
+			SpeakerLabel: "0",
+			StartTime:    durationpb.New(0),
+			EndTime:      durationpb.New(time.Second),
+			Transcript:   string(b),
+		}},
+	}}})
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	for _, poll := range []time.Duration{0, 20 * time.Millisecond} {
+		dir, err := ioutil.TempDir("", "juzu-watch")
+		if err != nil {
+			t.Fatalf("could not create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(dir)
+
+		// a file present at startup, whose result name is taken, and
+		// whose name is taken in the done directory
+		for path, data := range map[string]string{"a.wav": "first", "a.txt": "notes", "done/a.wav": "old"} {
+			if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, path), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		format, _ := output.Lookup("text")
+		w := &watcher{
+			dir:         dir,
+			doneDir:     filepath.Join(dir, "done"),
+			failedDir:   filepath.Join(dir, "failed"),
+			exts:        []string{".wav"},
+			stable:      100 * time.Millisecond,
+			poll:        poll,
+			concurrency: 2,
+			cfg:         &juzupb.DiarizationConfig{},
+			format:      format,
+			runner:      &batch.Runner{Client: mockDiarizer{}, Attempts: 1},
+			log:         log.New(ioutil.Discard, "", 0),
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- w.run(ctx) }()
+
+		// a file written slowly, which must only be diarized once complete
+		time.Sleep(50 * time.Millisecond)
+		f, err := os.Create(filepath.Join(dir, "b.wav"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{"sec", "ond"} {
+			_, _ = f.WriteString(s)
+			time.Sleep(60 * time.Millisecond)
+		}
+		_ = f.Close()
+
+		// a file that fails, and one that is not audio
+		_ = ioutil.WriteFile(filepath.Join(dir, "c.wav"), []byte("bad"), 0644)
+		_ = ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+
+		want := map[string]string{
+			"a.txt":        "notes",
+			"a_2.txt":      "[00:00:00.000 - 00:00:01.000] 0: first\n",
+			"done/a.wav":   "old",
+			"done/a_2.wav": "first",
+			"b.txt":        "[00:00:00.000 - 00:00:01.000] 0: second\n",
+			"done/b.wav":   "second",
+			"c.error":      "streaming diarization failed: bad audio\n",
+			"failed/c.wav": "bad",
+			"notes.txt":    "ignored",
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		var got map[string]string
+		for time.Now().Before(deadline) {
+			got = readTree(t, dir)
+			if fmt.Sprint(got) == fmt.Sprint(want) {
+				break
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("poll %v: watcher failed: %v", poll, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("poll %v: directory holds\n%v\nwant\n%v", poll, got, want)
+		}
+	}
+}
+
+// readTree returns the contents of all files under dir, by relative path.
+func readTree(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	_ = filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil // moved meanwhile
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[strings.Replace(rel, string(filepath.Separator), "/", -1)] = string(b)
+		return nil
+	})
+	return files
+}
+
+func TestWatcherResultWriteFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "juzu-watch")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.wav")
+	if err := ioutil.WriteFile(path, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{"done", "failed"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// results are written to a directory that does not exist
+	format, _ := output.Lookup("text")
+	format.Ext = "/missing" + format.Ext
+	w := &watcher{
+		dir:       dir,
+		doneDir:   filepath.Join(dir, "done"),
+		failedDir: filepath.Join(dir, "failed"),
+		format:    format,
+		runner:    &batch.Runner{Client: mockDiarizer{}, Attempts: 1},
+		log:       log.New(ioutil.Discard, "", 0),
+		active:    map[string]bool{path: true},
+	}
+	w.process(context.Background(), path)
+
+	want := map[string]string{"failed/a.wav": "first"}
+	if got := readTree(t, dir); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("directory holds\n%v\nwant\n%v", got, want)
+	}
+	if w.active[path] {
+		t.Errorf("file is still active")
+	}
+}
+
+func TestWatcherIgnoresResults(t *testing.T) {
+	format, _ := output.Lookup("text")
+	w := &watcher{format: format, pending: map[string]fileState{}, active: map[string]bool{}}
+	for _, name := range []string{"a.txt", "a_2.TXT", "a.error", ".a.txt.tmp", "a.wav"} {
+		w.seen(filepath.Join("dir", name))
+	}
+	if _, ok := w.pending[filepath.Join("dir", "a.wav")]; len(w.pending) != 1 || !ok {
+		t.Errorf("pending files are %v; want only a.wav", w.pending)
+	}
+
+	if err := setup(&watcher{dir: "dir", concurrency: 1}, nil, nil, ".wav,.txt", "text", 1); err == nil {
+		t.Errorf("diarizing files with the extension of results: want error, got nil")
+	}
+}
+
+func TestWatcherMoveFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "juzu-watch")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.wav")
+	if err := ioutil.WriteFile(path, []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the done directory does not exist
+	var logged strings.Builder
+	format, _ := output.Lookup("text")
+	w := &watcher{
+		dir:       dir,
+		doneDir:   filepath.Join(dir, "done"),
+		failedDir: filepath.Join(dir, "failed"),
+		format:    format,
+		runner:    &batch.Runner{Client: mockDiarizer{}, Attempts: 1},
+		log:       log.New(&logged, "", 0),
+		active:    map[string]bool{path: true},
+	}
+	w.process(context.Background(), path)
+
+	want := map[string]string{"a.wav": "first"}
+	if got := readTree(t, dir); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("directory holds\n%v\nwant\n%v", got, want)
+	}
+	if w.active[path] {
+		t.Errorf("file is still active")
+	}
+	if !strings.Contains(logged.String(), "unable to move file") {
+		t.Errorf("move error was not logged: %q", logged.String())
+	}
+}
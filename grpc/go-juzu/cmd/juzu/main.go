@@ -0,0 +1,238 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command juzu is a command line client of juzu server.
+//
+// Usage:
+//
+//	juzu version [flags]
+//	juzu models [flags]
+//	juzu diarize [flags] <file|->
+//
+// Run "juzu <command> -h" for the flags of each command.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/cmd/internal/flags"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/cmd/internal/output"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// command is a subcommand of juzu.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(fs *flag.FlagSet, args []string, e *env) error
+}
+
+var commands = []command{
+	{"version", "", "print the versions of the server", version},
+	{"models", "", "list the diarization models of the server", models},
+	{"diarize", "<file|->", "diarize an audio file, or the standard input", diarize},
+}
+
+// env holds the standard streams of the command.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], &env{os.Stdin, os.Stdout, os.Stderr}))
+}
+
+// run runs the command given by args, and returns the exit status.
+func run(args []string, e *env) int {
+	usage := func() {
+		fmt.Fprintf(e.stderr, "Usage: juzu <command> [flags] [args]\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(e.stderr, "  %-10s %s\n", c.name, c.summary)
+		}
+		fmt.Fprintf(e.stderr, "\nRun \"juzu <command> -h\" for the flags of each command.\n")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+
+		fs := flag.NewFlagSet("juzu "+c.name, flag.ContinueOnError)
+		fs.SetOutput(e.stderr)
+		fs.Usage = func() {
+			fmt.Fprintf(e.stderr, "Usage: juzu %s [flags] %s\n\n%s.\n\nFlags:\n", c.name, c.args, c.summary)
+			fs.PrintDefaults()
+		}
+		if err := c.run(fs, args[1:], e); err != nil {
+			// usage errors have already been reported by the flag set
+			if err == flag.ErrHelp {
+				return 2
+			}
+			fmt.Fprintf(e.stderr, "juzu %s: %v\n", c.name, err)
+			return 1
+		}
+		return 0
+	}
+
+	if args[0] != "-h" && args[0] != "help" {
+		fmt.Fprintf(e.stderr, "juzu: unknown command %q\n\n", args[0])
+	}
+	usage()
+	return 2
+}
+
+// printJSON prints the message in protobuf JSON.
+func printJSON(w io.Writer, m proto.Message) error {
+	b, err := protojson.MarshalOptions{Multiline: true}.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func version(fs *flag.FlagSet, args []string, e *env) error {
+	var client flags.Client
+	client.Register(fs)
+	asJSON := fs.Bool("json", false, "print the versions in JSON")
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+
+	c, err := client.Dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	v, err := c.Version()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(e.stdout, v)
+	}
+	_, err = fmt.Fprintf(e.stdout, "juzu: %s\nserver: %s\n", v.Juzu, v.Server)
+	return err
+}
+
+func models(fs *flag.FlagSet, args []string, e *env) error {
+	var client flags.Client
+	client.Register(fs)
+	asJSON := fs.Bool("json", false, "print the models in JSON instead of a table")
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+
+	c, err := client.Dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	resp, err := c.ListModels()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(e.stdout, resp)
+	}
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSAMPLE RATE\tSEGMENTATION\tCUBIC MODELS")
+	for _, m := range resp.Models {
+		a := m.GetAttributes()
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", m.Id, m.Name, a.GetSampleRate(), a.GetSegmentationType(),
+			strings.Join(a.GetCompatibleCubicModels(), ","))
+	}
+	return tw.Flush()
+}
+
+func diarize(fs *flag.FlagSet, args []string, e *env) error {
+	var (
+		client flags.Client
+		config flags.Config
+	)
+	client.Register(fs)
+	config.Register(fs, "wav")
+	format := fs.String("format", "text",
+		fmt.Sprintf("format of the results (%s)", strings.Join(output.Names(), ", ")))
+	out := fs.String("o", "", "file to write the results to, instead of the standard output")
+	if err := fs.Parse(args); err != nil {
+		return flag.ErrHelp
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	f, err := output.Lookup(*format)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.DiarizationConfig()
+	if err != nil {
+		return err
+	}
+
+	name, audio := fs.Arg(0), e.stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		audio = file
+	}
+
+	c, err := client.Dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var responses []*juzupb.DiarizationResponse
+	err = c.StreamingDiarize(context.Background(), cfg, audio, func(resp *juzupb.DiarizationResponse) {
+		responses = append(responses, resp)
+	})
+	if err != nil {
+		return err
+	}
+
+	results := output.FinalResults(responses)
+	if *out == "" {
+		return f.Write(e.stdout, name, results)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf, name, results); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(*out, buf.Bytes(), 0644)
+}
@@ -0,0 +1,154 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// mockServer returns the audio and model of diarization calls as the
+// transcript and speaker label of a single segment.
+type mockServer struct {
+	juzupb.UnimplementedJuzuServer
+}
+
+func (mockServer) Version(context.Context, *emptypb.Empty) (*juzupb.VersionResponse, error) {
+	return &juzupb.VersionResponse{Juzu: "1.2.3", Server: "4.5.6"}, nil
+}
+
+func (mockServer) ListModels(context.Context, *emptypb.Empty) (*juzupb.ListModelsResponse, error) {
+	return &juzupb.ListModelsResponse{Models: []*juzupb.Model{{
+		Id:   "1",
+		Name: "General",
+		Attributes: &juzupb.ModelAttributes{
+			SampleRate:            8000,
+			SegmentationType:      "variable",
+			CompatibleCubicModels: []string{"a", "b"},
+		},
+	}}}, nil
+}
+
+func (mockServer) StreamingDiarize(stream juzupb.Juzu_StreamingDiarizeServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	cfg := msg.GetConfig()
+
+	var audio []byte
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		audio = append(audio, msg.GetAudio().GetData()...)
+	}
+
+	return stream.Send(&juzupb.DiarizationResponse{Results: []*juzupb.DiarizationResult{{
+		Segments: []*juzupb.Segment{{
+			SpeakerLabel: fmt.Sprintf("%s/%v", cfg.ModelId, cfg.AudioEncoding),
+			StartTime:    durationpb.New(1500 * time.Millisecond),
+			EndTime:      durationpb.New(62 * time.Second),
+			Transcript:   string(audio),
+		}},
+	}}})
+}
+
+func TestRun(t *testing.T) {
+	lis, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+	s := grpc.NewServer()
+	juzupb.RegisterJuzuServer(s, mockServer{})
+	go func() { _ = s.Serve(lis) }()
+	defer s.Stop()
+	server := fmt.Sprintf("-server=localhost:%d", lis.Addr().(*net.TCPAddr).Port)
+
+	dir, err := ioutil.TempDir("", "juzu")
+	if err != nil {
+		t.Fatalf("could not create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	audioFile := filepath.Join(dir, "audio.wav")
+	if err := ioutil.WriteFile(audioFile, []byte("from file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outFile := filepath.Join(dir, "out.txt")
+
+	for _, tc := range []struct {
+		args   []string
+		stdin  string
+		status int
+		stdout string
+	}{
+		{[]string{"version", server, "-insecure"}, "", 0, "juzu: 1.2.3\nserver: 4.5.6\n"},
+		{[]string{"models", server, "-insecure"}, "", 0,
+			"ID  NAME     SAMPLE RATE  SEGMENTATION  CUBIC MODELS\n" +
+				"1   General  8000         variable      a,b\n"},
+		{[]string{"diarize", server, "-insecure", "-model", "1", audioFile}, "", 0,
+			"[00:00:01.500 - 00:01:02.000] 1/WAV: from file\n"},
+		{[]string{"diarize", server, "-insecure", "-model", "2", "-encoding", "raw_linear16", "-"}, "from stdin", 0,
+			"[00:00:01.500 - 00:01:02.000] 2/RAW_LINEAR16: from stdin\n"},
+		{[]string{"diarize", server, "-insecure", "-model", "1", "-o", outFile, "-"}, "to file", 0, ""},
+
+		// errors
+		{[]string{"diarize", server, "-insecure", audioFile}, "", 1, ""},
+		{[]string{"diarize", server, "-insecure", "-model", "1", "-format", "nope", audioFile}, "", 1, ""},
+		{[]string{"diarize", server, "-insecure", "-model", "1"}, "", 2, ""},
+		{[]string{"models", "-bad-flag"}, "", 2, ""},
+		{[]string{"unknown"}, "", 2, ""},
+		{nil, "", 2, ""},
+	} {
+		var stdout, stderr bytes.Buffer
+		status := run(tc.args, &env{strings.NewReader(tc.stdin), &stdout, &stderr})
+		if status != tc.status {
+			t.Errorf("juzu %v: exit status %d; want %d (stderr: %s)", tc.args, status, tc.status, stderr.String())
+		}
+		if stdout.String() != tc.stdout {
+			t.Errorf("juzu %v: output\n%s\nwant\n%s", tc.args, stdout.String(), tc.stdout)
+		}
+	}
+
+	b, err := ioutil.ReadFile(outFile)
+	if err != nil || string(b) != "[00:00:01.500 - 00:01:02.000] 1/WAV: to file\n" {
+		t.Errorf("juzu diarize -o: file holds %q (%v)", b, err)
+	}
+
+	// models as JSON
+	var stdout bytes.Buffer
+	if status := run([]string{"models", server, "-insecure", "-json"}, &env{nil, &stdout, ioutil.Discard}); status != 0 ||
+		!strings.Contains(stdout.String(), `"compatibleCubicModels"`) {
+		t.Errorf("juzu models -json: exit status %d, output %s", status, stdout.String())
+	}
+}
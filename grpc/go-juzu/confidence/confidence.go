@@ -0,0 +1,167 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package confidence uses the confidence of the words of diarized transcripts
+// to drop or flag the words that were likely misrecognized, such as for human
+// review.
+//
+// Word confidences are estimates between 0 and 1, and words are low
+// confidence when their estimate is below a threshold.  Segments without
+// words have no confidence, and are left as they are.
+package confidence
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/golang/protobuf/proto"
+)
+
+// Average returns the mean confidence of the words, and false if there are
+// none.
+func Average(words []*juzupb.WordInfo) (float64, bool) {
+	if len(words) == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, w := range words {
+		sum += w.Confidence
+	}
+	return sum / float64(len(words)), true
+}
+
+// SpeakerAverages returns the mean confidence of the words of each speaker.
+// Speakers without words are absent.
+func SpeakerAverages(segments []*juzupb.Segment) map[string]float64 {
+	words := make(map[string][]*juzupb.WordInfo)
+	for _, s := range segments {
+		words[s.SpeakerLabel] = append(words[s.SpeakerLabel], s.Words...)
+	}
+	avg := make(map[string]float64)
+	for spk, ws := range words {
+		if a, ok := Average(ws); ok {
+			avg[spk] = a
+		}
+	}
+	return avg
+}
+
+// Low returns the indexes of the words of the segment whose confidence is
+// below the threshold.
+func Low(s *juzupb.Segment, threshold float64) []int {
+	var low []int
+	for i, w := range s.Words {
+		if w.Confidence < threshold {
+			low = append(low, i)
+		}
+	}
+	return low
+}
+
+// Filter returns copies of the segments without the words whose confidence
+// is below the threshold, and with transcripts rebuilt from the words left.
+// Segments left without words are dropped.
+func Filter(segments []*juzupb.Segment, threshold float64) []*juzupb.Segment {
+	var out []*juzupb.Segment
+	for _, s := range segments {
+		if len(s.Words) == 0 {
+			out = append(out, s)
+			continue
+		}
+
+		low := Low(s, threshold)
+		if len(low) == len(s.Words) {
+			continue
+		}
+		c := proto.Clone(s).(*juzupb.Segment)
+		if len(low) > 0 {
+			text := transcriptWords(s)
+			c.Words = c.Words[:0]
+			var kept []string
+			for i, w := range s.Words {
+				if w.Confidence >= threshold {
+					c.Words = append(c.Words, proto.Clone(w).(*juzupb.WordInfo))
+					kept = append(kept, text[i])
+				}
+			}
+			c.Transcript = strings.Join(kept, " ")
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// Marker returns the text of a low confidence word as it is shown.
+type Marker func(word string, confidence float64) string
+
+// Brackets marks words by enclosing them in square brackets.
+func Brackets(word string, confidence float64) string {
+	return "[" + word + "]"
+}
+
+// Highlight returns the transcript of the segment with the words whose
+// confidence is below the threshold marked.
+func Highlight(s *juzupb.Segment, threshold float64, mark Marker) string {
+	if len(s.Words) == 0 {
+		return s.Transcript
+	}
+	text := transcriptWords(s)
+	for _, i := range Low(s, threshold) {
+		text[i] = mark(text[i], s.Words[i].Confidence)
+	}
+	return strings.Join(text, " ")
+}
+
+// transcriptWords returns the text of each word of the segment, taken from
+// the transcript, which may be formatted, if it has as many words as there
+// are word timestamps.
+func transcriptWords(s *juzupb.Segment) []string {
+	if text := strings.Fields(s.Transcript); len(text) == len(s.Words) {
+		return text
+	}
+	text := make([]string, len(s.Words))
+	for i, w := range s.Words {
+		text[i] = w.Word
+	}
+	return text
+}
+
+// WriteReview writes one line per segment, with its times, speaker, average
+// confidence and transcript with low confidence words marked, as in:
+//
+//	[00:00:01.500 - 00:00:04.000] 1 (0.82): please [call] me back
+//
+// Segments without words have no average confidence.
+func WriteReview(w io.Writer, segments []*juzupb.Segment, threshold float64, mark Marker) error {
+	for _, s := range segments {
+		conf := ""
+		if avg, ok := Average(s.Words); ok {
+			conf = fmt.Sprintf(" (%.2f)", avg)
+		}
+		if _, err := fmt.Fprintf(w, "[%s - %s] %s%s: %s\n", formatTime(s.StartTime.AsDuration()),
+			formatTime(s.EndTime.AsDuration()), s.SpeakerLabel, conf, Highlight(s, threshold, mark)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatTime formats t as hours, minutes, seconds and milliseconds.
+func formatTime(t time.Duration) string {
+	ms := t.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,117 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package confidence_test
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/confidence"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// segment returns a segment with the given transcript, and words with the
+// given confidences, of 500ms each from the given start.
+func segment(label string, start time.Duration, transcript string, conf ...float64) *juzupb.Segment {
+	s := &juzupb.Segment{SpeakerLabel: label, StartTime: durationpb.New(start), Transcript: transcript}
+	for i, w := range strings.Fields(transcript) {
+		s.Words = append(s.Words, &juzupb.WordInfo{
+			Word:       strings.ToLower(strings.Trim(w, ".,")),
+			Confidence: conf[i],
+			StartTime:  durationpb.New(start),
+			Duration:   durationpb.New(500 * time.Millisecond),
+		})
+		start += 500 * time.Millisecond
+	}
+	s.EndTime = durationpb.New(start)
+	return s
+}
+
+func segments() []*juzupb.Segment {
+	noWords := segment("2", 3*time.Second, "")
+	noWords.Transcript = "Bye."
+	return []*juzupb.Segment{
+		segment("1", 0, "Please call me back.", 0.9, 0.3, 0.8, 0.7),
+		segment("2", 2*time.Second, "Sure", 0.2),
+		noWords,
+		segment("1", 4*time.Second, "Thanks.", 1),
+	}
+}
+
+func TestAverages(t *testing.T) {
+	segs := segments()
+	if avg, ok := confidence.Average(segs[0].Words); !ok || math.Abs(avg-0.675) > 1e-9 {
+		t.Errorf("average is %v, %v; want 0.675", avg, ok)
+	}
+	if _, ok := confidence.Average(nil); ok {
+		t.Errorf("no words have an average")
+	}
+
+	got := confidence.SpeakerAverages(segs)
+	if len(got) != 2 || math.Abs(got["1"]-0.74) > 1e-9 || got["2"] != 0.2 {
+		t.Errorf("speaker averages are %v; want 1: 0.74, 2: 0.2", got)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	segs := segments()
+	got := confidence.Filter(segs, 0.5)
+
+	var desc []string
+	for _, s := range got {
+		var words []string
+		for _, w := range s.Words {
+			words = append(words, w.Word)
+		}
+		desc = append(desc, fmt.Sprintf("%s: %s %q", s.SpeakerLabel, s.Transcript, words))
+	}
+	want := []string{
+		`1: Please me back. ["please" "me" "back"]`,
+		`2: Bye. []`,
+		`1: Thanks. ["thanks"]`,
+	}
+	if !reflect.DeepEqual(desc, want) {
+		t.Errorf("Filter = %q; want %q", desc, want)
+	}
+	if len(segs[0].Words) != 4 {
+		t.Errorf("Filter modified its input")
+	}
+}
+
+func TestWriteReview(t *testing.T) {
+	var buf bytes.Buffer
+	mark := func(w string, c float64) string { return fmt.Sprintf("%s(%.1f)", w, c) }
+	if err := confidence.WriteReview(&buf, segments(), 0.75, mark); err != nil {
+		t.Fatal(err)
+	}
+	want := `[00:00:00.000 - 00:00:02.000] 1 (0.68): Please call(0.3) me back.(0.7)
+[00:00:02.000 - 00:00:02.500] 2 (0.20): Sure(0.2)
+[00:00:03.000 - 00:00:03.000] 2: Bye.
+[00:00:04.000 - 00:00:04.500] 1 (1.00): Thanks.
+`
+	if buf.String() != want {
+		t.Errorf("review is\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	if got := confidence.Highlight(segments()[0], 0.5, confidence.Brackets); got != "Please [call] me back." {
+		t.Errorf("Highlight = %q", got)
+	}
+}
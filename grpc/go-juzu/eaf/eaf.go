@@ -0,0 +1,214 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eaf writes and reads diarization segments as ELAN annotation
+// documents (EAF).
+//
+// Each speaker has a tier of their segments, named after their label, with
+// the transcripts as annotations, and, if the segments have words, a tier of
+// their words, named after their label followed by "/words".  Both tiers have
+// the speaker as participant.  Word confidences are not kept.
+package eaf
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/tier"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// linguisticType is the linguistic type of the tiers that are written.
+const linguisticType = "default-lt"
+
+// Options configures the documents that are written.
+type Options struct {
+	// MediaURL is the URL of the audio, such as "file:///data/call.wav".
+	// If empty, the document has no linked media.
+	MediaURL string
+
+	// MIMEType of the audio.  If empty, "audio/x-wav" is used.
+	MIMEType string
+
+	// Date of the document.  If zero, the current time is used.
+	Date time.Time
+}
+
+type document struct {
+	XMLName xml.Name            `xml:"ANNOTATION_DOCUMENT"`
+	Author  string              `xml:"AUTHOR,attr"`
+	Date    string              `xml:"DATE,attr"`
+	Format  string              `xml:"FORMAT,attr"`
+	Version string              `xml:"VERSION,attr"`
+	XSI     string              `xml:"xmlns:xsi,attr,omitempty"`
+	Schema  string              `xml:"xsi:noNamespaceSchemaLocation,attr,omitempty"`
+	Header  header              `xml:"HEADER"`
+	Slots   []slot              `xml:"TIME_ORDER>TIME_SLOT"`
+	Tiers   []tierXML           `xml:"TIER"`
+	Types   []linguisticTypeXML `xml:"LINGUISTIC_TYPE"`
+}
+
+type header struct {
+	MediaFile string  `xml:"MEDIA_FILE,attr"`
+	TimeUnits string  `xml:"TIME_UNITS,attr"`
+	Media     []media `xml:"MEDIA_DESCRIPTOR"`
+}
+
+type media struct {
+	URL      string `xml:"MEDIA_URL,attr"`
+	MIMEType string `xml:"MIME_TYPE,attr"`
+}
+
+type slot struct {
+	ID    string `xml:"TIME_SLOT_ID,attr"`
+	Value *int64 `xml:"TIME_VALUE,attr"`
+}
+
+type tierXML struct {
+	ID             string       `xml:"TIER_ID,attr"`
+	Participant    string       `xml:"PARTICIPANT,attr,omitempty"`
+	LinguisticType string       `xml:"LINGUISTIC_TYPE_REF,attr"`
+	Annotations    []annotation `xml:"ANNOTATION"`
+}
+
+type annotation struct {
+	Alignable *alignable `xml:"ALIGNABLE_ANNOTATION"`
+}
+
+type alignable struct {
+	ID    string `xml:"ANNOTATION_ID,attr"`
+	Slot1 string `xml:"TIME_SLOT_REF1,attr"`
+	Slot2 string `xml:"TIME_SLOT_REF2,attr"`
+	Value string `xml:"ANNOTATION_VALUE"`
+}
+
+type linguisticTypeXML struct {
+	ID        string `xml:"LINGUISTIC_TYPE_ID,attr"`
+	Alignable bool   `xml:"TIME_ALIGNABLE,attr"`
+	Graphic   bool   `xml:"GRAPHIC_REFERENCES,attr"`
+}
+
+// Write writes the segments as an EAF document.  Times are written to the
+// millisecond.
+func Write(w io.Writer, segments []*juzupb.Segment, opts Options) error {
+	if opts.MIMEType == "" {
+		opts.MIMEType = "audio/x-wav"
+	}
+	if opts.Date.IsZero() {
+		opts.Date = time.Now()
+	}
+
+	doc := document{
+		Date:    opts.Date.Format(time.RFC3339),
+		Format:  "3.0",
+		Version: "3.0",
+		XSI:     "http://www.w3.org/2001/XMLSchema-instance",
+		Schema:  "http://www.mpi.nl/tools/elan/EAFv3.0.xsd",
+		Header:  header{TimeUnits: "milliseconds"},
+		Types:   []linguisticTypeXML{{ID: linguisticType, Alignable: true}},
+	}
+	if opts.MediaURL != "" {
+		doc.Header.Media = []media{{URL: opts.MediaURL, MIMEType: opts.MIMEType}}
+	}
+
+	// Time slots are numbered in order of time, as ELAN does.
+	type ref struct{ tier, annotation, end int }
+	type point struct {
+		ms  int64
+		ref ref
+	}
+	var points []point
+	for i, t := range tier.FromSegments(segments) {
+		x := tierXML{ID: t.Name, Participant: t.Speaker, LinguisticType: linguisticType}
+		for j, iv := range t.Intervals {
+			x.Annotations = append(x.Annotations, annotation{&alignable{Value: iv.Text}})
+			points = append(points,
+				point{iv.Start.Milliseconds(), ref{i, j, 0}},
+				point{iv.End.Milliseconds(), ref{i, j, 1}})
+		}
+		doc.Tiers = append(doc.Tiers, x)
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].ms < points[j].ms })
+	for i, p := range points {
+		id := fmt.Sprintf("ts%d", i+1)
+		ms := p.ms
+		doc.Slots = append(doc.Slots, slot{ID: id, Value: &ms})
+		a := doc.Tiers[p.ref.tier].Annotations[p.ref.annotation].Alignable
+		if p.ref.end == 0 {
+			a.Slot1 = id
+		} else {
+			a.Slot2 = id
+		}
+	}
+	n := 0
+	for _, t := range doc.Tiers {
+		for _, a := range t.Annotations {
+			n++
+			a.Alignable.ID = fmt.Sprintf("a%d", n)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "    ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Read parses an EAF document and returns its segments sorted by start time.
+// Words are added to the segment of their speaker they overlap the most.
+// Annotations that are not time-aligned, such as those of symbolic tiers, or
+// whose times are unknown, are skipped.
+func Read(r io.Reader) ([]*juzupb.Segment, error) {
+	var doc document
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("unable to parse EAF document: %v", err)
+	}
+	if doc.Header.TimeUnits != "" && doc.Header.TimeUnits != "milliseconds" {
+		return nil, fmt.Errorf("unsupported time units %q", doc.Header.TimeUnits)
+	}
+
+	slots := make(map[string]time.Duration)
+	for _, s := range doc.Slots {
+		if s.Value != nil {
+			slots[s.ID] = time.Duration(*s.Value) * time.Millisecond
+		}
+	}
+
+	var tiers []*tier.Tier
+	for _, x := range doc.Tiers {
+		t := &tier.Tier{Name: x.ID, Speaker: x.Participant}
+		for _, a := range x.Annotations {
+			if a.Alignable == nil {
+				continue
+			}
+			start, ok1 := slots[a.Alignable.Slot1]
+			end, ok2 := slots[a.Alignable.Slot2]
+			if !ok1 || !ok2 {
+				continue
+			}
+			t.Intervals = append(t.Intervals, tier.Interval{Start: start, End: end, Text: a.Alignable.Value})
+		}
+		tiers = append(tiers, t)
+	}
+	return tier.ToSegments(tiers), nil
+}
@@ -0,0 +1,202 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eaf_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/eaf"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func ms(n int) *durationpb.Duration {
+	return durationpb.New(time.Duration(n) * time.Millisecond)
+}
+
+var segments = []*juzupb.Segment{
+	{
+		SpeakerLabel: "1",
+		StartTime:    ms(500),
+		EndTime:      ms(2000),
+		Transcript:   "say hi",
+		Words: []*juzupb.WordInfo{
+			{Word: "say", StartTime: ms(500), Duration: ms(500)},
+			{Word: "hi", StartTime: ms(1000), Duration: ms(1000)},
+		},
+	},
+	{SpeakerLabel: "2", StartTime: ms(1500), EndTime: ms(3000), Transcript: "yes"},
+}
+
+const want = `<?xml version="1.0" encoding="UTF-8"?>
+<ANNOTATION_DOCUMENT AUTHOR="" DATE="2021-06-01T12:00:00Z" FORMAT="3.0" VERSION="3.0" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:noNamespaceSchemaLocation="http://www.mpi.nl/tools/elan/EAFv3.0.xsd">
+    <HEADER MEDIA_FILE="" TIME_UNITS="milliseconds">
+        <MEDIA_DESCRIPTOR MEDIA_URL="file:///data/call.wav" MIME_TYPE="audio/x-wav"></MEDIA_DESCRIPTOR>
+    </HEADER>
+    <TIME_ORDER>
+        <TIME_SLOT TIME_SLOT_ID="ts1" TIME_VALUE="500"></TIME_SLOT>
+        <TIME_SLOT TIME_SLOT_ID="ts2" TIME_VALUE="500"></TIME_SLOT>
+        <TIME_SLOT TIME_SLOT_ID="ts3" TIME_VALUE="1000"></TIME_SLOT>
+        <TIME_SLOT TIME_SLOT_ID="ts4" TIME_VALUE="1000"></TIME_SLOT>
+        <TIME_SLOT TIME_SLOT_ID="ts5" TIME_VALUE="1500"></TIME_SLOT>
+        <TIME_SLOT TIME_SLOT_ID="ts6" TIME_VALUE="2000"></TIME_SLOT>
+        <TIME_SLOT TIME_SLOT_ID="ts7" TIME_VALUE="2000"></TIME_SLOT>
+        <TIME_SLOT TIME_SLOT_ID="ts8" TIME_VALUE="3000"></TIME_SLOT>
+    </TIME_ORDER>
+    <TIER TIER_ID="1" PARTICIPANT="1" LINGUISTIC_TYPE_REF="default-lt">
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a1" TIME_SLOT_REF1="ts1" TIME_SLOT_REF2="ts6">
+                <ANNOTATION_VALUE>say hi</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+    </TIER>
+    <TIER TIER_ID="1/words" PARTICIPANT="1" LINGUISTIC_TYPE_REF="default-lt">
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a2" TIME_SLOT_REF1="ts2" TIME_SLOT_REF2="ts3">
+                <ANNOTATION_VALUE>say</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a3" TIME_SLOT_REF1="ts4" TIME_SLOT_REF2="ts7">
+                <ANNOTATION_VALUE>hi</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+    </TIER>
+    <TIER TIER_ID="2" PARTICIPANT="2" LINGUISTIC_TYPE_REF="default-lt">
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a4" TIME_SLOT_REF1="ts5" TIME_SLOT_REF2="ts8">
+                <ANNOTATION_VALUE>yes</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+    </TIER>
+    <LINGUISTIC_TYPE LINGUISTIC_TYPE_ID="default-lt" TIME_ALIGNABLE="true" GRAPHIC_REFERENCES="false"></LINGUISTIC_TYPE>
+</ANNOTATION_DOCUMENT>
+`
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	opts := eaf.Options{MediaURL: "file:///data/call.wav", Date: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}
+	if err := eaf.Write(&b, segments, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	got, err := eaf.Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSegments(t, got, segments)
+}
+
+func TestRead(t *testing.T) {
+	// As written by ELAN, with a symbolic tier, an unaligned time slot and
+	// a gap.
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<ANNOTATION_DOCUMENT AUTHOR="" DATE="2021-06-01T12:00:00+00:00" FORMAT="3.0" VERSION="3.0">
+    <HEADER MEDIA_FILE="" TIME_UNITS="milliseconds"/>
+    <TIME_ORDER>
+        <TIME_SLOT TIME_SLOT_ID="ts1" TIME_VALUE="0"/>
+        <TIME_SLOT TIME_SLOT_ID="ts2" TIME_VALUE="1200"/>
+        <TIME_SLOT TIME_SLOT_ID="ts3"/>
+        <TIME_SLOT TIME_SLOT_ID="ts4" TIME_VALUE="2500"/>
+    </TIME_ORDER>
+    <TIER LINGUISTIC_TYPE_REF="default-lt" PARTICIPANT="Ann" TIER_ID="Ann">
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a1" TIME_SLOT_REF1="ts1" TIME_SLOT_REF2="ts2">
+                <ANNOTATION_VALUE>good morning</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a2" TIME_SLOT_REF1="ts2" TIME_SLOT_REF2="ts3">
+                <ANNOTATION_VALUE>unaligned</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a3" TIME_SLOT_REF1="ts2" TIME_SLOT_REF2="ts4">
+                <ANNOTATION_VALUE></ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+    </TIER>
+    <TIER LINGUISTIC_TYPE_REF="translation" PARENT_REF="Ann" TIER_ID="Ann-translation">
+        <ANNOTATION>
+            <REF_ANNOTATION ANNOTATION_ID="a4" ANNOTATION_REF="a1">
+                <ANNOTATION_VALUE>bonjour</ANNOTATION_VALUE>
+            </REF_ANNOTATION>
+        </ANNOTATION>
+    </TIER>
+    <TIER LINGUISTIC_TYPE_REF="default-lt" TIER_ID="Ann/words">
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a5" TIME_SLOT_REF1="ts1" TIME_SLOT_REF2="ts2">
+                <ANNOTATION_VALUE>morning</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+        <ANNOTATION>
+            <ALIGNABLE_ANNOTATION ANNOTATION_ID="a6" TIME_SLOT_REF1="ts2" TIME_SLOT_REF2="ts4">
+                <ANNOTATION_VALUE>stray</ANNOTATION_VALUE>
+            </ALIGNABLE_ANNOTATION>
+        </ANNOTATION>
+    </TIER>
+    <LINGUISTIC_TYPE GRAPHIC_REFERENCES="false" LINGUISTIC_TYPE_ID="default-lt" TIME_ALIGNABLE="true"/>
+    <LINGUISTIC_TYPE CONSTRAINTS="Symbolic_Association" GRAPHIC_REFERENCES="false" LINGUISTIC_TYPE_ID="translation" TIME_ALIGNABLE="false"/>
+</ANNOTATION_DOCUMENT>
+`
+	got, err := eaf.Read(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSegments(t, got, []*juzupb.Segment{{
+		SpeakerLabel: "Ann",
+		StartTime:    ms(0),
+		EndTime:      ms(1200),
+		Transcript:   "good morning",
+		Words:        []*juzupb.WordInfo{{Word: "morning", StartTime: ms(0), Duration: ms(1200)}},
+	}})
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not xml", "hello"},
+		{"time units", `<ANNOTATION_DOCUMENT><HEADER TIME_UNITS="NTSC-frames"/></ANNOTATION_DOCUMENT>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := eaf.Read(strings.NewReader(tt.doc)); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func checkSegments(t *testing.T, got, want []*juzupb.Segment) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("segment %d: got %v; want %v", i, got[i], want[i])
+		}
+	}
+}
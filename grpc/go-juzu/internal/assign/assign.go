@@ -0,0 +1,144 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package assign solves the assignment problem with the Hungarian algorithm.
+// It is used to find the best one-to-one mapping between two sets of speaker
+// labels.
+package assign
+
+import "math"
+
+// Maximize returns the assignment of rows to columns of the weight matrix w
+// that maximizes the total weight, where each row is assigned at most one
+// column and each column at most one row.  The returned slice holds the column
+// assigned to each row, or -1 for rows left unassigned because there are more
+// rows than columns.  The matrix may be rectangular, but all rows must have
+// the same length.
+func Maximize(w [][]float64) []int {
+	rows := len(w)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(w[0])
+
+	n := rows
+	if cols > n {
+		n = cols
+	}
+
+	// Turn the problem into minimizing a non-negative cost on a square
+	// matrix, padded with zero weights.
+	var max float64
+	for _, r := range w {
+		for _, v := range r {
+			if v > max {
+				max = v
+			}
+		}
+	}
+
+	cost := make([][]float64, n)
+	for i := range cost {
+		cost[i] = make([]float64, n)
+		for j := range cost[i] {
+			cost[i][j] = max
+			if i < rows && j < cols {
+				cost[i][j] = max - w[i][j]
+			}
+		}
+	}
+
+	colOf := hungarian(cost)
+
+	res := make([]int, rows)
+	for i := range res {
+		res[i] = colOf[i]
+		if res[i] >= cols {
+			res[i] = -1
+		}
+	}
+	return res
+}
+
+// hungarian returns the column assigned to each row of the square cost
+// matrix in a minimum cost assignment.  This is the O(n^3) formulation using
+// row and column potentials.
+func hungarian(cost [][]float64) []int {
+	n := len(cost)
+
+	// The arrays below are 1-indexed, with index 0 standing for a virtual
+	// unassigned row.
+	u := make([]float64, n+1)
+	v := make([]float64, n+1)
+	rowOf := make([]int, n+1) // row assigned to each column
+	way := make([]int, n+1)
+
+	for i := 1; i <= n; i++ {
+		rowOf[0] = i
+		j0 := 0
+		minv := make([]float64, n+1)
+		used := make([]bool, n+1)
+		for j := range minv {
+			minv[j] = math.Inf(1)
+		}
+
+		for {
+			used[j0] = true
+			i0 := rowOf[j0]
+			delta := math.Inf(1)
+			j1 := 0
+			for j := 1; j <= n; j++ {
+				if used[j] {
+					continue
+				}
+				cur := cost[i0-1][j-1] - u[i0] - v[j]
+				if cur < minv[j] {
+					minv[j] = cur
+					way[j] = j0
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					j1 = j
+				}
+			}
+
+			for j := 0; j <= n; j++ {
+				if used[j] {
+					u[rowOf[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+
+			j0 = j1
+			if rowOf[j0] == 0 {
+				break
+			}
+		}
+
+		// augment along the path found
+		for j0 != 0 {
+			j1 := way[j0]
+			rowOf[j0] = rowOf[j1]
+			j0 = j1
+		}
+	}
+
+	colOf := make([]int, n)
+	for j := 1; j <= n; j++ {
+		colOf[rowOf[j]-1] = j - 1
+	}
+	return colOf
+}
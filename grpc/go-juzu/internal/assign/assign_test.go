@@ -0,0 +1,49 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package assign
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaximize(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		w    [][]float64
+		want []int
+	}{
+		{"empty", nil, nil},
+		{"identity", [][]float64{{1, 0}, {0, 1}}, []int{0, 1}},
+		{"swap", [][]float64{{0, 5}, {5, 0}}, []int{1, 0}},
+		{
+			// greedy would pick 9 first and end up with 9+1
+			"not greedy",
+			[][]float64{{9, 8}, {8, 1}},
+			[]int{1, 0},
+		},
+		{"more columns", [][]float64{{1, 2, 9}}, []int{2}},
+		{"more rows", [][]float64{{1}, {7}, {3}}, []int{-1, 0, -1}},
+		{
+			"three",
+			[][]float64{{7, 5, 11}, {5, 4, 1}, {9, 3, 2}},
+			[]int{2, 1, 0},
+		},
+	} {
+		if got := Maximize(tc.w); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Maximize(%v) = %v; want %v", tc.name, tc.w, got, tc.want)
+		}
+	}
+}
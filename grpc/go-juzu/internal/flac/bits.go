@@ -0,0 +1,105 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flac
+
+// bitWriter accumulates a big-endian bit stream in memory.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64 // bits not yet moved to buf, right aligned
+	nbits uint   // number of bits in acc
+}
+
+func (w *bitWriter) reset() {
+	w.buf = w.buf[:0]
+	w.acc = 0
+	w.nbits = 0
+}
+
+// writeBits writes the n (<= 32) least significant bits of v.
+func (w *bitWriter) writeBits(v uint64, n uint) {
+	if n == 0 {
+		return
+	}
+	if n > 32 {
+		w.writeBits(v>>32, n-32)
+		n = 32
+	}
+
+	w.acc = w.acc<<n | v&(1<<n-1)
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.nbits -= 8
+		w.buf = append(w.buf, byte(w.acc>>w.nbits))
+	}
+}
+
+// writeUnary writes v zero bits followed by a one bit.
+func (w *bitWriter) writeUnary(v uint64) {
+	for ; v >= 32; v -= 32 {
+		w.writeBits(0, 32)
+	}
+	w.writeBits(1, uint(v)+1)
+}
+
+func (w *bitWriter) writeBytes(b []byte) {
+	for _, c := range b {
+		w.writeBits(uint64(c), 8)
+	}
+}
+
+// align pads the stream with zero bits up to the next byte boundary.
+func (w *bitWriter) align() {
+	if w.nbits > 0 {
+		w.writeBits(0, 8-w.nbits)
+	}
+}
+
+// bytes returns the complete bytes written so far.
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// crc8 computes the CRC-8 (polynomial 0x07) that protects FLAC frame headers.
+func crc8(b []byte) uint8 {
+	var crc uint8
+	for _, c := range b {
+		crc ^= c
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 computes the CRC-16 (polynomial 0x8005) that protects whole FLAC
+// frames.
+func crc16(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
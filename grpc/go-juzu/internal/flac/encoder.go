@@ -0,0 +1,324 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flac implements a streaming FLAC encoder for 16-bit mono linear PCM.
+//
+// The encoder only uses the fixed linear predictors of the FLAC format.  This
+// gives most of the compression that is possible for speech at a fraction of
+// the complexity of full LPC analysis, and it keeps the encoder fast enough to
+// run on the fly while audio is being streamed.
+package flac
+
+import (
+	"fmt"
+	"io"
+)
+
+// Block sizes (number of samples per FLAC frame) accepted by NewEncoder.
+// DefaultBlockSize is used when no block size is given.
+const (
+	DefaultBlockSize = 4096
+	MinBlockSize     = 16
+	MaxBlockSize     = 65535
+)
+
+const (
+	maxSampleRate = 655350
+	maxRiceParam  = 14
+)
+
+// Encoder encodes raw (headerless) 16-bit signed little endian mono samples
+// into a FLAC stream.
+//
+// The stream header and each encoded frame are written to the underlying
+// writer using a single Write call each, so every Write a caller sees holds a
+// whole number of FLAC frames.
+type Encoder struct {
+	w          io.Writer
+	sampleRate int
+	blockSize  int
+
+	headerDone bool
+	frameNum   uint64
+
+	pending []byte  // trailing byte of an incomplete sample
+	samples []int32 // samples of the frame being filled
+	bw      bitWriter
+}
+
+// NewEncoder returns an Encoder that writes a FLAC stream to w.  A blockSize
+// of 0 selects DefaultBlockSize.
+func NewEncoder(w io.Writer, sampleRate int, blockSize int) (*Encoder, error) {
+	if sampleRate <= 0 || sampleRate > maxSampleRate {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+
+	if blockSize == 0 {
+		blockSize = DefaultBlockSize
+	}
+
+	// FLAC requires blocks of at least 16 samples, except for the last one.
+	if blockSize < MinBlockSize || blockSize > MaxBlockSize {
+		return nil, fmt.Errorf("invalid block size %d", blockSize)
+	}
+
+	return &Encoder{
+		w:          w,
+		sampleRate: sampleRate,
+		blockSize:  blockSize,
+		samples:    make([]int32, 0, blockSize),
+	}, nil
+}
+
+// Write buffers the given PCM data, and encodes and writes out every frame
+// that has been completed by it.  Samples may be split across calls.
+func (e *Encoder) Write(p []byte) (int, error) {
+	n := len(p)
+
+	if len(e.pending) > 0 && len(p) > 0 {
+		e.pending = append(e.pending, p[0])
+		p = p[1:]
+		if err := e.addSample(e.pending); err != nil {
+			return 0, err
+		}
+		e.pending = e.pending[:0]
+	}
+
+	for len(p) >= 2 {
+		if err := e.addSample(p); err != nil {
+			return 0, err
+		}
+		p = p[2:]
+	}
+
+	if len(p) > 0 {
+		e.pending = append(e.pending, p[0])
+	}
+
+	return n, nil
+}
+
+// Close encodes and writes out any buffered samples as the final frame.  A
+// trailing byte that does not make up a whole sample is discarded.  Close does
+// not close the underlying writer.
+func (e *Encoder) Close() error {
+	e.pending = e.pending[:0]
+
+	if len(e.samples) > 0 {
+		return e.flush()
+	}
+
+	if !e.headerDone {
+		return e.writeHeader()
+	}
+
+	return nil
+}
+
+func (e *Encoder) addSample(b []byte) error {
+	e.samples = append(e.samples, int32(int16(uint16(b[0])|uint16(b[1])<<8)))
+	if len(e.samples) == e.blockSize {
+		return e.flush()
+	}
+	return nil
+}
+
+// writeHeader writes the "fLaC" marker and the STREAMINFO metadata block.
+// Since the encoder works on a stream, the total number of samples, the frame
+// sizes and the MD5 signature are unknown and left as zero, which the format
+// allows.
+func (e *Encoder) writeHeader() error {
+	e.headerDone = true
+
+	bw := &e.bw
+	bw.reset()
+	bw.writeBytes([]byte("fLaC"))
+
+	bw.writeBits(1, 1)   // last metadata block
+	bw.writeBits(0, 7)   // block type: STREAMINFO
+	bw.writeBits(34, 24) // length
+
+	bw.writeBits(uint64(e.blockSize), 16) // minimum block size
+	bw.writeBits(uint64(e.blockSize), 16) // maximum block size
+	bw.writeBits(0, 24)                   // minimum frame size
+	bw.writeBits(0, 24)                   // maximum frame size
+	bw.writeBits(uint64(e.sampleRate), 20)
+	bw.writeBits(0, 3)  // channels - 1
+	bw.writeBits(15, 5) // bits per sample - 1
+	bw.writeBits(0, 36) // total samples
+	bw.writeBytes(make([]byte, 16))
+
+	_, err := e.w.Write(bw.bytes())
+	return err
+}
+
+// flush encodes the buffered samples as one frame and writes it out.
+func (e *Encoder) flush() error {
+	if !e.headerDone {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+
+	bw := &e.bw
+	bw.reset()
+
+	// frame header
+	bw.writeBits(0x3ffe, 14) // sync code
+	bw.writeBits(0, 1)       // reserved
+	bw.writeBits(0, 1)       // fixed block size
+	bw.writeBits(7, 4)       // block size stored as 16 bits at end of header
+	bw.writeBits(0, 4)       // sample rate taken from STREAMINFO
+	bw.writeBits(0, 4)       // one channel
+	bw.writeBits(4, 3)       // 16 bits per sample
+	bw.writeBits(0, 1)       // reserved
+	bw.writeBytes(encodeUTF8(e.frameNum))
+	bw.writeBits(uint64(len(e.samples)-1), 16)
+	bw.writeBits(uint64(crc8(bw.bytes())), 8)
+
+	encodeSubframe(bw, e.samples)
+
+	bw.align()
+	bw.writeBits(uint64(crc16(bw.bytes())), 16)
+
+	e.frameNum++
+	e.samples = e.samples[:0]
+
+	_, err := e.w.Write(bw.bytes())
+	return err
+}
+
+// encodeSubframe writes the samples using whichever of the CONSTANT, FIXED or
+// VERBATIM subframe types is the smallest.
+func encodeSubframe(bw *bitWriter, samples []int32) {
+	constant := true
+	for _, s := range samples[1:] {
+		if s != samples[0] {
+			constant = false
+			break
+		}
+	}
+
+	if constant {
+		bw.writeBits(0, 1) // padding
+		bw.writeBits(0, 6) // CONSTANT
+		bw.writeBits(0, 1) // no wasted bits
+		bw.writeBits(uint64(uint16(samples[0])), 16)
+		return
+	}
+
+	bestOrder, bestBits := -1, 16*len(samples) // VERBATIM
+	var residuals [5][]int32
+	var params [5]uint
+	for order := 0; order <= 4 && order < len(samples); order++ {
+		residuals[order] = fixedResiduals(samples, order)
+		param, bits := riceParam(residuals[order])
+		params[order] = param
+		bits += 16*order + 2 + 4 + 4
+		if bits < bestBits {
+			bestOrder, bestBits = order, bits
+		}
+	}
+
+	bw.writeBits(0, 1) // padding
+	if bestOrder < 0 {
+		bw.writeBits(1, 6) // VERBATIM
+		bw.writeBits(0, 1)
+		for _, s := range samples {
+			bw.writeBits(uint64(uint16(s)), 16)
+		}
+		return
+	}
+
+	bw.writeBits(uint64(8|bestOrder), 6) // FIXED
+	bw.writeBits(0, 1)
+	for _, s := range samples[:bestOrder] {
+		bw.writeBits(uint64(uint16(s)), 16)
+	}
+
+	bw.writeBits(0, 2) // residual coded with 4-bit Rice parameters
+	bw.writeBits(0, 4) // partition order 0
+	k := params[bestOrder]
+	bw.writeBits(uint64(k), 4)
+	for _, r := range residuals[bestOrder] {
+		u := zigzag(r)
+		bw.writeUnary(u >> k)
+		bw.writeBits(u&(1<<k-1), k)
+	}
+}
+
+// fixedResiduals returns the residuals of the samples following the first
+// order ones, using the fixed polynomial predictor of the given order.
+func fixedResiduals(s []int32, order int) []int32 {
+	r := make([]int32, 0, len(s)-order)
+	for i := order; i < len(s); i++ {
+		var p int32
+		switch order {
+		case 1:
+			p = s[i-1]
+		case 2:
+			p = 2*s[i-1] - s[i-2]
+		case 3:
+			p = 3*s[i-1] - 3*s[i-2] + s[i-3]
+		case 4:
+			p = 4*s[i-1] - 6*s[i-2] + 4*s[i-3] - s[i-4]
+		}
+		r = append(r, s[i]-p)
+	}
+	return r
+}
+
+// riceParam returns the Rice parameter that codes the residuals in the
+// fewest bits, and that number of bits.
+func riceParam(residuals []int32) (uint, int) {
+	var sum uint64
+	for _, r := range residuals {
+		sum += zigzag(r)
+	}
+
+	best, bestBits := uint(0), -1
+	for k := uint(0); k <= maxRiceParam; k++ {
+		bits := int(sum>>k) + len(residuals)*int(k+1)
+		if bestBits < 0 || bits < bestBits {
+			best, bestBits = k, bits
+		}
+	}
+	return best, bestBits
+}
+
+func zigzag(r int32) uint64 {
+	return uint64(uint32(r<<1) ^ uint32(r>>31))
+}
+
+// encodeUTF8 codes the frame number the way FLAC frame headers expect, which
+// is an extension of UTF-8 to 36 bit values.
+func encodeUTF8(v uint64) []byte {
+	if v < 0x80 {
+		return []byte{byte(v)}
+	}
+
+	// number of continuation bytes
+	n := 1
+	for v >= 1<<(5*uint(n)+6) {
+		n++
+	}
+
+	b := make([]byte, n+1)
+	for i := n; i > 0; i-- {
+		b[i] = 0x80 | byte(v&0x3f)
+		v >>= 6
+	}
+	b[0] = byte(0xff<<uint(7-n)) | byte(v)
+	return b
+}
@@ -0,0 +1,223 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flac
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Test that encoded audio decodes back to the original samples.  The decoder
+// below only understands the subset of FLAC that the encoder produces.
+func TestEncoder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	for _, n := range []int{0, 1, 15, 4096, 4097, 20000} {
+		samples := make([]int16, n)
+		for i := range samples {
+			v := 8000*math.Sin(float64(i)/20) + rnd.NormFloat64()*300
+			switch {
+			case i > 5000 && i < 9000:
+				v = 0 // constant frames
+			case i%3000 == 7:
+				v = math.MaxInt16
+			case i%3000 == 8:
+				v = math.MinInt16
+			}
+			samples[i] = int16(v)
+		}
+
+		pcm := make([]byte, 2*n)
+		for i, s := range samples {
+			pcm[2*i] = byte(uint16(s))
+			pcm[2*i+1] = byte(uint16(s) >> 8)
+		}
+
+		var out bytes.Buffer
+		var writes int
+		e, err := NewEncoder(writeFunc(func(p []byte) (int, error) {
+			writes++
+			return out.Write(p)
+		}), 16000, 0)
+		if err != nil {
+			t.Fatalf("could not create encoder: %v", err)
+		}
+
+		// write in odd sized pieces, so that samples are split across writes
+		for i := 0; i < len(pcm); i += 777 {
+			j := i + 777
+			if j > len(pcm) {
+				j = len(pcm)
+			}
+			if _, err := e.Write(pcm[i:j]); err != nil {
+				t.Fatalf("n=%d: write failed: %v", n, err)
+			}
+		}
+		if err := e.Close(); err != nil {
+			t.Fatalf("n=%d: close failed: %v", n, err)
+		}
+
+		// one write for the header and one for each frame
+		if want := 1 + (n+DefaultBlockSize-1)/DefaultBlockSize; writes != want {
+			t.Errorf("n=%d: got %d writes; want %d", n, writes, want)
+		}
+
+		got, err := decode(out.Bytes())
+		if err != nil {
+			t.Fatalf("n=%d: could not decode: %v", n, err)
+		}
+
+		if len(got) != len(samples) {
+			t.Fatalf("n=%d: decoded %d samples; want %d", n, len(got), len(samples))
+		}
+		for i := range got {
+			if got[i] != samples[i] {
+				t.Fatalf("n=%d: sample %d is %d; want %d", n, i, got[i], samples[i])
+			}
+		}
+	}
+}
+
+func TestNewEncoder_Invalid(t *testing.T) {
+	if _, err := NewEncoder(&bytes.Buffer{}, 0, 0); err == nil {
+		t.Errorf("encoder with sample rate 0: want error, got nil")
+	}
+	if _, err := NewEncoder(&bytes.Buffer{}, 16000, 8); err == nil {
+		t.Errorf("encoder with block size 8: want error, got nil")
+	}
+}
+
+func TestEncodeUTF8(t *testing.T) {
+	for _, tc := range []struct {
+		v    uint64
+		want []byte
+	}{
+		{0x00, []byte{0x00}},
+		{0x7f, []byte{0x7f}},
+		{0x80, []byte{0xc2, 0x80}},
+		{0x7ff, []byte{0xdf, 0xbf}},
+		{0x800, []byte{0xe0, 0xa0, 0x80}},
+		{0x10000, []byte{0xf0, 0x90, 0x80, 0x80}},
+	} {
+		if got := encodeUTF8(tc.v); !bytes.Equal(got, tc.want) {
+			t.Errorf("encodeUTF8(%#x) = %x; want %x", tc.v, got, tc.want)
+		}
+	}
+}
+
+type writeFunc func([]byte) (int, error)
+
+func (f writeFunc) Write(p []byte) (int, error) { return f(p) }
+
+// decode decodes a FLAC stream as produced by Encoder, verifying checksums.
+func decode(b []byte) ([]int16, error) {
+	if len(b) < 42 || string(b[:4]) != "fLaC" {
+		return nil, fmt.Errorf("missing stream header")
+	}
+	b = b[42:]
+
+	var samples []int16
+	for len(b) > 0 {
+		r := &bitReader{buf: b}
+		if r.read(14) != 0x3ffe {
+			return nil, fmt.Errorf("missing frame sync code")
+		}
+		r.read(18) // constant header fields
+
+		// frame number; test streams are short enough for single byte
+		// frame numbers.
+		if r.read(8)&0x80 != 0 {
+			return nil, fmt.Errorf("unexpected frame number in test data")
+		}
+		blockSize := int(r.read(16)) + 1
+		if crc8(b[:r.pos/8]) != uint8(r.read(8)) {
+			return nil, fmt.Errorf("header checksum mismatch")
+		}
+
+		r.read(1)
+		typ := r.read(6)
+		r.read(1)
+		switch {
+		case typ == 0:
+			v := int16(r.read(16))
+			for i := 0; i < blockSize; i++ {
+				samples = append(samples, v)
+			}
+		case typ == 1:
+			for i := 0; i < blockSize; i++ {
+				samples = append(samples, int16(r.read(16)))
+			}
+		case typ&0x38 == 8:
+			order := int(typ & 7)
+			s := make([]int32, 0, blockSize)
+			for i := 0; i < order; i++ {
+				s = append(s, int32(int16(r.read(16))))
+			}
+			if r.read(2) != 0 || r.read(4) != 0 {
+				return nil, fmt.Errorf("unexpected residual coding")
+			}
+			k := uint(r.read(4))
+			for i := order; i < blockSize; i++ {
+				q := uint64(0)
+				for r.read(1) == 0 {
+					q++
+				}
+				u := q<<k | r.read(k)
+				res := int32(u>>1) ^ -int32(u&1)
+				s = append(s, res+predict(s[i-order:i]))
+			}
+			for _, v := range s {
+				samples = append(samples, int16(v))
+			}
+		default:
+			return nil, fmt.Errorf("unexpected subframe type %d", typ)
+		}
+
+		if r.pos%8 != 0 {
+			r.read(8 - r.pos%8)
+		}
+		if crc16(b[:r.pos/8]) != uint16(r.read(16)) {
+			return nil, fmt.Errorf("frame checksum mismatch")
+		}
+		b = b[r.pos/8:]
+	}
+	return samples, nil
+}
+
+// predict returns the fixed polynomial prediction of the sample following the
+// given ones, whose number is the predictor order.
+func predict(s []int32) int32 {
+	// fixedResiduals predicts the last of the given samples, so predicting
+	// a zero sample gives the negated prediction.
+	return -fixedResiduals(append(s[:len(s):len(s)], 0), len(s))[0]
+}
+
+type bitReader struct {
+	buf []byte
+	pos uint
+}
+
+func (r *bitReader) read(n uint) uint64 {
+	var v uint64
+	for i := uint(0); i < n; i++ {
+		bit := r.buf[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v
+}
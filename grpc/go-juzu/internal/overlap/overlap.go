@@ -0,0 +1,144 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package overlap finds and cleans up regions of overlapping speech of
+// diarization results.
+package overlap
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Find returns the regions where segments of different speakers overlap in
+// time, in order.  Each region holds the labels of its speakers, sorted, and
+// adjacent regions have different speakers.
+func Find(segments []*juzupb.Segment) []*juzupb.OverlapRegion {
+	// Segments start and end at these times.  Ends come before starts at
+	// the same time, so that segments that only touch do not overlap.
+	type event struct {
+		t     time.Duration
+		label string
+		delta int
+	}
+	var events []event
+	for _, s := range segments {
+		start, end := s.StartTime.AsDuration(), s.EndTime.AsDuration()
+		if end > start {
+			events = append(events, event{start, s.SpeakerLabel, 1}, event{end, s.SpeakerLabel, -1})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].t != events[j].t {
+			return events[i].t < events[j].t
+		}
+		return events[i].delta < events[j].delta
+	})
+
+	var regions []*juzupb.OverlapRegion
+	var last []string // speakers of the last region, if it is still open
+	active := make(map[string]int)
+	for i, e := range events {
+		active[e.label] += e.delta
+		if active[e.label] == 0 {
+			delete(active, e.label)
+		}
+		if i+1 < len(events) && events[i+1].t == e.t {
+			continue
+		}
+
+		var speakers []string
+		if len(active) > 1 {
+			for l := range active {
+				speakers = append(speakers, l)
+			}
+			sort.Strings(speakers)
+		}
+		if equalLabels(speakers, last) {
+			continue
+		}
+
+		if last != nil {
+			regions[len(regions)-1].EndTime = durationpb.New(e.t)
+		}
+		if speakers != nil {
+			regions = append(regions, &juzupb.OverlapRegion{StartTime: durationpb.New(e.t), SpeakerLabels: speakers})
+		}
+		last = speakers
+	}
+	return regions
+}
+
+// Clean removes duplicate labels from the regions, as left when speakers are
+// merged, drops the regions left with a single speaker, and joins touching
+// regions of the same speakers.  The regions are modified in place.
+func Clean(regions []*juzupb.OverlapRegion) []*juzupb.OverlapRegion {
+	var out []*juzupb.OverlapRegion
+	for _, r := range regions {
+		seen := make(map[string]bool)
+		labels := r.SpeakerLabels[:0]
+		for _, l := range r.SpeakerLabels {
+			if !seen[l] {
+				seen[l] = true
+				labels = append(labels, l)
+			}
+		}
+		r.SpeakerLabels = labels
+		if len(labels) < 2 {
+			continue
+		}
+
+		if n := len(out); n > 0 {
+			last := out[n-1]
+			if last.EndTime.AsDuration() == r.StartTime.AsDuration() && sameLabels(last.SpeakerLabels, labels) {
+				last.EndTime = r.EndTime
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func equalLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameLabels tells whether a and b hold the same labels, in any order.
+func sameLabels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	in := make(map[string]bool)
+	for _, l := range a {
+		in[l] = true
+	}
+	for _, l := range b {
+		if !in[l] {
+			return false
+		}
+	}
+	return true
+}
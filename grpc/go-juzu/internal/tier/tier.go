@@ -0,0 +1,167 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tier converts diarization segments to and from the interval tiers
+// of annotation tools, such as Praat and ELAN.
+//
+// Each speaker has a tier of their segments, named after their label, and, if
+// the segments have words, a tier of words named after their label followed
+// by WordsSuffix.
+package tier
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// WordsSuffix ends the names of word tiers.
+const WordsSuffix = "/words"
+
+// Interval is an annotated time interval.
+type Interval struct {
+	Start, End time.Duration
+	Text       string
+}
+
+// Tier is a named sequence of intervals, in order.
+type Tier struct {
+	Name string
+
+	// Speaker is the label of the speaker of the tier, if known.
+	Speaker string
+
+	Intervals []Interval
+}
+
+// FromSegments returns the tiers of the segments, by speaker in order of first
+// appearance, with the tier of the segments of each speaker followed by the
+// tier of their words, if any.  Intervals are sorted by start time.
+func FromSegments(segments []*juzupb.Segment) []*Tier {
+	var tiers []*Tier
+	segTiers := make(map[string]*Tier)
+	wordTiers := make(map[string]*Tier)
+	for _, s := range segments {
+		label := s.SpeakerLabel
+		t := segTiers[label]
+		if t == nil {
+			t = &Tier{Name: label, Speaker: label}
+			segTiers[label] = t
+			tiers = append(tiers, t)
+		}
+		t.Intervals = append(t.Intervals, Interval{s.StartTime.AsDuration(), s.EndTime.AsDuration(), s.Transcript})
+
+		if len(s.Words) > 0 && wordTiers[label] == nil {
+			wordTiers[label] = &Tier{Name: label + WordsSuffix, Speaker: label}
+		}
+		for _, w := range s.Words {
+			start := w.StartTime.AsDuration()
+			wt := wordTiers[label]
+			wt.Intervals = append(wt.Intervals, Interval{start, start + w.Duration.AsDuration(), w.Word})
+		}
+	}
+
+	var out []*Tier
+	for _, t := range tiers {
+		out = append(out, t)
+		if wt := wordTiers[t.Speaker]; wt != nil {
+			out = append(out, wt)
+		}
+	}
+	for _, t := range out {
+		sort.SliceStable(t.Intervals, func(i, j int) bool { return t.Intervals[i].Start < t.Intervals[j].Start })
+	}
+	return out
+}
+
+// ToSegments returns the segments of the tiers, sorted by start time.  Tiers
+// whose names end with WordsSuffix hold the words of the speaker whose label
+// is the rest of the name; each word goes to the segment of that speaker it
+// overlaps the most, and words outside all of them are dropped.  Intervals with
+// empty text are gaps, and are skipped.
+func ToSegments(tiers []*Tier) []*juzupb.Segment {
+	var segments []*juzupb.Segment
+	bySpeaker := make(map[string][]*juzupb.Segment)
+	for _, t := range tiers {
+		if strings.HasSuffix(t.Name, WordsSuffix) {
+			continue
+		}
+		for _, iv := range t.Intervals {
+			if strings.TrimSpace(iv.Text) == "" {
+				continue
+			}
+			s := &juzupb.Segment{
+				SpeakerLabel: t.Name,
+				StartTime:    durationpb.New(iv.Start),
+				EndTime:      durationpb.New(iv.End),
+				Transcript:   iv.Text,
+			}
+			segments = append(segments, s)
+			bySpeaker[t.Name] = append(bySpeaker[t.Name], s)
+		}
+	}
+
+	for _, t := range tiers {
+		if !strings.HasSuffix(t.Name, WordsSuffix) {
+			continue
+		}
+		segs := bySpeaker[strings.TrimSuffix(t.Name, WordsSuffix)]
+		for _, iv := range t.Intervals {
+			if strings.TrimSpace(iv.Text) == "" {
+				continue
+			}
+			// Words without duration only need to be within the segment.
+			var best *juzupb.Segment
+			var bestOverlap time.Duration
+			for _, s := range segs {
+				if o := overlap(iv, s); o > bestOverlap || best == nil && o == 0 && iv.Start == iv.End {
+					best, bestOverlap = s, o
+				}
+			}
+			if best == nil {
+				continue
+			}
+			best.Words = append(best.Words, &juzupb.WordInfo{
+				Word:      iv.Text,
+				StartTime: durationpb.New(iv.Start),
+				Duration:  durationpb.New(iv.End - iv.Start),
+			})
+		}
+	}
+
+	for _, s := range segments {
+		sort.SliceStable(s.Words, func(i, j int) bool {
+			return s.Words[i].StartTime.AsDuration() < s.Words[j].StartTime.AsDuration()
+		})
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].StartTime.AsDuration() < segments[j].StartTime.AsDuration()
+	})
+	return segments
+}
+
+// overlap returns the duration of the overlap of the interval and segment.
+func overlap(iv Interval, s *juzupb.Segment) time.Duration {
+	start, end := s.StartTime.AsDuration(), s.EndTime.AsDuration()
+	if iv.Start > start {
+		start = iv.Start
+	}
+	if iv.End < end {
+		end = iv.End
+	}
+	return end - start
+}
@@ -650,6 +650,14 @@ type DiarizationResult struct {
 	// Servers are not required to implement support for returning partial
 	// results, and clients should generally not depend on their availability.
 	IsPartial bool `protobuf:"varint,3,opt,name=is_partial,json=isPartial,proto3" json:"is_partial,omitempty"`
+	// Regions of audio where several speakers talk at the same time, in order
+	// of start time.  The segments of the speakers of a region overlap in time
+	// over it.
+	//
+	// Servers are not required to implement support for detecting overlapping
+	// speech.  When this is empty, clients may still find the regions where
+	// segments of different speakers overlap.
+	Overlaps []*OverlapRegion `protobuf:"bytes,4,rep,name=overlaps,proto3" json:"overlaps,omitempty"`
 }
 
 func (x *DiarizationResult) Reset() {
@@ -705,6 +713,13 @@ func (x *DiarizationResult) GetIsPartial() bool {
 	return false
 }
 
+func (x *DiarizationResult) GetOverlaps() []*OverlapRegion {
+	if x != nil {
+		return x.Overlaps
+	}
+	return nil
+}
+
 // A diarized segment of audio.
 type Segment struct {
 	state         protoimpl.MessageState
@@ -793,6 +808,76 @@ func (x *Segment) GetWords() []*WordInfo {
 	return nil
 }
 
+// A region of audio where several speakers talk at the same time.
+type OverlapRegion struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Time offset relative to the beginning of audio received by the diarizer
+	// and corresponding to the start of this region.
+	StartTime *durationpb.Duration `protobuf:"bytes,1,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	// Time offset relative to the beginning of audio received by the diarizer
+	// and corresponding to the end of this region.
+	EndTime *durationpb.Duration `protobuf:"bytes,2,opt,name=end_time,json=endTime,proto3" json:"end_time,omitempty"`
+	// The identities of the speakers active over the whole region; there are
+	// at least two.
+	SpeakerLabels []string `protobuf:"bytes,3,rep,name=speaker_labels,json=speakerLabels,proto3" json:"speaker_labels,omitempty"`
+}
+
+func (x *OverlapRegion) Reset() {
+	*x = OverlapRegion{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_juzu_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OverlapRegion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OverlapRegion) ProtoMessage() {}
+
+func (x *OverlapRegion) ProtoReflect() protoreflect.Message {
+	mi := &file_juzu_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OverlapRegion.ProtoReflect.Descriptor instead.
+func (*OverlapRegion) Descriptor() ([]byte, []int) {
+	return file_juzu_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *OverlapRegion) GetStartTime() *durationpb.Duration {
+	if x != nil {
+		return x.StartTime
+	}
+	return nil
+}
+
+func (x *OverlapRegion) GetEndTime() *durationpb.Duration {
+	if x != nil {
+		return x.EndTime
+	}
+	return nil
+}
+
+func (x *OverlapRegion) GetSpeakerLabels() []string {
+	if x != nil {
+		return x.SpeakerLabels
+	}
+	return nil
+}
+
 // Word-specific information for recognized words.
 type WordInfo struct {
 	state         protoimpl.MessageState
@@ -814,7 +899,7 @@ type WordInfo struct {
 func (x *WordInfo) Reset() {
 	*x = WordInfo{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_juzu_proto_msgTypes[10]
+		mi := &file_juzu_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -827,7 +912,7 @@ func (x *WordInfo) String() string {
 func (*WordInfo) ProtoMessage() {}
 
 func (x *WordInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_juzu_proto_msgTypes[10]
+	mi := &file_juzu_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -840,7 +925,7 @@ func (x *WordInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WordInfo.ProtoReflect.Descriptor instead.
 func (*WordInfo) Descriptor() ([]byte, []int) {
-	return file_juzu_proto_rawDescGZIP(), []int{10}
+	return file_juzu_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *WordInfo) GetWord() string {
@@ -946,7 +1031,7 @@ var file_juzu_proto_rawDesc = []byte{
 	0x56, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x46, 0x4c, 0x41, 0x43, 0x10, 0x02, 0x12, 0x07, 0x0a,
 	0x03, 0x4d, 0x50, 0x33, 0x10, 0x03, 0x22, 0x26, 0x0a, 0x10, 0x44, 0x69, 0x61, 0x72, 0x69, 0x7a,
 	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x41, 0x75, 0x64, 0x69, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61,
-	0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x91,
+	0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0xcf,
 	0x01, 0x0a, 0x11, 0x44, 0x69, 0x61, 0x72, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
 	0x73, 0x75, 0x6c, 0x74, 0x12, 0x36, 0x0a, 0x08, 0x73, 0x65, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x73,
 	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73,
@@ -956,56 +1041,71 @@ var file_juzu_proto_rawDesc = []byte{
 	0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x70, 0x65, 0x61, 0x6b, 0x65, 0x72, 0x4c, 0x61, 0x62,
 	0x65, 0x6c, 0x73, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x73, 0x5f, 0x70, 0x61, 0x72, 0x74, 0x69, 0x61,
 	0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x69, 0x73, 0x50, 0x61, 0x72, 0x74, 0x69,
-	0x61, 0x6c, 0x22, 0xf1, 0x01, 0x0a, 0x07, 0x53, 0x65, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x23,
-	0x0a, 0x0d, 0x73, 0x70, 0x65, 0x61, 0x6b, 0x65, 0x72, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x73, 0x70, 0x65, 0x61, 0x6b, 0x65, 0x72, 0x4c, 0x61,
-	0x62, 0x65, 0x6c, 0x12, 0x38, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d,
-	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
-	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69,
-	0x6f, 0x6e, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x34, 0x0a,
-	0x08, 0x65, 0x6e, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
-	0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54,
-	0x69, 0x6d, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70,
-	0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72,
-	0x69, 0x70, 0x74, 0x12, 0x31, 0x0a, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65, 0x65, 0x63,
-	0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52,
-	0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x22, 0xaf, 0x01, 0x0a, 0x08, 0x57, 0x6f, 0x72, 0x64, 0x49,
-	0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x77, 0x6f, 0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x04, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69,
-	0x64, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0a, 0x63, 0x6f, 0x6e,
-	0x66, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x38, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74,
-	0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f,
-	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75,
-	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d,
-	0x65, 0x12, 0x35, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08,
-	0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x32, 0xcc, 0x02, 0x0a, 0x04, 0x4a, 0x75, 0x7a,
-	0x75, 0x12, 0x5b, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x2e, 0x67,
-	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45,
-	0x6d, 0x70, 0x74, 0x79, 0x1a, 0x22, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65,
-	0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x14, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x0e,
-	0x12, 0x0c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x64,
-	0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x12, 0x16, 0x2e, 0x67,
-	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45,
-	0x6d, 0x70, 0x74, 0x79, 0x1a, 0x25, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65,
-	0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64,
-	0x65, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x17, 0x82, 0xd3, 0xe4,
-	0x93, 0x02, 0x11, 0x12, 0x0f, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x6c, 0x69, 0x73, 0x74, 0x6d, 0x6f,
-	0x64, 0x65, 0x6c, 0x73, 0x12, 0x80, 0x01, 0x0a, 0x10, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69,
-	0x6e, 0x67, 0x44, 0x69, 0x61, 0x72, 0x69, 0x7a, 0x65, 0x12, 0x2a, 0x2e, 0x63, 0x6f, 0x62, 0x61,
-	0x6c, 0x74, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x53, 0x74,
-	0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x44, 0x69, 0x61, 0x72, 0x69, 0x7a, 0x65, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70,
-	0x65, 0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x44, 0x69, 0x61, 0x72, 0x69, 0x7a,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x14, 0x82,
-	0xd3, 0xe4, 0x93, 0x02, 0x0e, 0x12, 0x0c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x64, 0x69, 0x61, 0x72,
-	0x69, 0x7a, 0x65, 0x28, 0x01, 0x30, 0x01, 0x42, 0x1f, 0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x6a, 0x75,
-	0x7a, 0x75, 0x70, 0x62, 0xaa, 0x02, 0x11, 0x43, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x53, 0x70, 0x65,
-	0x65, 0x63, 0x68, 0x2e, 0x4a, 0x75, 0x7a, 0x75, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x61, 0x6c, 0x12, 0x3c, 0x0a, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x6c, 0x61, 0x70, 0x73, 0x18, 0x04,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65,
+	0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x4f, 0x76, 0x65, 0x72, 0x6c, 0x61, 0x70,
+	0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x6f, 0x76, 0x65, 0x72, 0x6c, 0x61, 0x70, 0x73,
+	0x22, 0xf1, 0x01, 0x0a, 0x07, 0x53, 0x65, 0x67, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x23, 0x0a, 0x0d,
+	0x73, 0x70, 0x65, 0x61, 0x6b, 0x65, 0x72, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x73, 0x70, 0x65, 0x61, 0x6b, 0x65, 0x72, 0x4c, 0x61, 0x62, 0x65,
+	0x6c, 0x12, 0x38, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x34, 0x0a, 0x08, 0x65,
+	0x6e, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x65, 0x6e, 0x64, 0x54, 0x69, 0x6d,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x12, 0x31, 0x0a, 0x05, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x1b, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x2e,
+	0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x57, 0x6f, 0x72, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x05, 0x77,
+	0x6f, 0x72, 0x64, 0x73, 0x22, 0xa6, 0x01, 0x0a, 0x0d, 0x4f, 0x76, 0x65, 0x72, 0x6c, 0x61, 0x70,
+	0x52, 0x65, 0x67, 0x69, 0x6f, 0x6e, 0x12, 0x38, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f,
+	0x74, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65,
+	0x12, 0x34, 0x0a, 0x08, 0x65, 0x6e, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x65,
+	0x6e, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x70, 0x65, 0x61, 0x6b, 0x65,
+	0x72, 0x5f, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0d,
+	0x73, 0x70, 0x65, 0x61, 0x6b, 0x65, 0x72, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x22, 0xaf, 0x01,
+	0x0a, 0x08, 0x57, 0x6f, 0x72, 0x64, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x77, 0x6f,
+	0x72, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x77, 0x6f, 0x72, 0x64, 0x12, 0x1e,
+	0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x64, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x38,
+	0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x73,
+	0x74, 0x61, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x35, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x32,
+	0xcc, 0x02, 0x0a, 0x04, 0x4a, 0x75, 0x7a, 0x75, 0x12, 0x5b, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x22, 0x2e, 0x63, 0x6f,
+	0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x14, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x0e, 0x12, 0x0c, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x64, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64,
+	0x65, 0x6c, 0x73, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x25, 0x2e, 0x63, 0x6f,
+	0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x17, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x11, 0x12, 0x0f, 0x2f, 0x61, 0x70, 0x69,
+	0x2f, 0x6c, 0x69, 0x73, 0x74, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x73, 0x12, 0x80, 0x01, 0x0a, 0x10,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x44, 0x69, 0x61, 0x72, 0x69, 0x7a, 0x65,
+	0x12, 0x2a, 0x2e, 0x63, 0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x2e,
+	0x6a, 0x75, 0x7a, 0x75, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x44, 0x69,
+	0x61, 0x72, 0x69, 0x7a, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x26, 0x2e, 0x63,
+	0x6f, 0x62, 0x61, 0x6c, 0x74, 0x73, 0x70, 0x65, 0x65, 0x63, 0x68, 0x2e, 0x6a, 0x75, 0x7a, 0x75,
+	0x2e, 0x44, 0x69, 0x61, 0x72, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x14, 0x82, 0xd3, 0xe4, 0x93, 0x02, 0x0e, 0x12, 0x0c, 0x2f, 0x61,
+	0x70, 0x69, 0x2f, 0x64, 0x69, 0x61, 0x72, 0x69, 0x7a, 0x65, 0x28, 0x01, 0x30, 0x01, 0x42, 0x1f,
+	0x5a, 0x09, 0x2e, 0x2f, 0x3b, 0x6a, 0x75, 0x7a, 0x75, 0x70, 0x62, 0xaa, 0x02, 0x11, 0x43, 0x6f,
+	0x62, 0x61, 0x6c, 0x74, 0x53, 0x70, 0x65, 0x65, 0x63, 0x68, 0x2e, 0x4a, 0x75, 0x7a, 0x75, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1021,7 +1121,7 @@ func file_juzu_proto_rawDescGZIP() []byte {
 }
 
 var file_juzu_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_juzu_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_juzu_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_juzu_proto_goTypes = []interface{}{
 	(DiarizationConfig_Encoding)(0), // 0: cobaltspeech.juzu.DiarizationConfig.Encoding
 	(*StreamingDiarizeRequest)(nil), // 1: cobaltspeech.juzu.StreamingDiarizeRequest
@@ -1034,9 +1134,10 @@ var file_juzu_proto_goTypes = []interface{}{
 	(*DiarizationAudio)(nil),        // 8: cobaltspeech.juzu.DiarizationAudio
 	(*DiarizationResult)(nil),       // 9: cobaltspeech.juzu.DiarizationResult
 	(*Segment)(nil),                 // 10: cobaltspeech.juzu.Segment
-	(*WordInfo)(nil),                // 11: cobaltspeech.juzu.WordInfo
-	(*durationpb.Duration)(nil),     // 12: google.protobuf.Duration
-	(*emptypb.Empty)(nil),           // 13: google.protobuf.Empty
+	(*OverlapRegion)(nil),           // 11: cobaltspeech.juzu.OverlapRegion
+	(*WordInfo)(nil),                // 12: cobaltspeech.juzu.WordInfo
+	(*durationpb.Duration)(nil),     // 13: google.protobuf.Duration
+	(*emptypb.Empty)(nil),           // 14: google.protobuf.Empty
 }
 var file_juzu_proto_depIdxs = []int32{
 	7,  // 0: cobaltspeech.juzu.StreamingDiarizeRequest.config:type_name -> cobaltspeech.juzu.DiarizationConfig
@@ -1046,22 +1147,25 @@ var file_juzu_proto_depIdxs = []int32{
 	9,  // 4: cobaltspeech.juzu.DiarizationResponse.results:type_name -> cobaltspeech.juzu.DiarizationResult
 	0,  // 5: cobaltspeech.juzu.DiarizationConfig.audio_encoding:type_name -> cobaltspeech.juzu.DiarizationConfig.Encoding
 	10, // 6: cobaltspeech.juzu.DiarizationResult.segments:type_name -> cobaltspeech.juzu.Segment
-	12, // 7: cobaltspeech.juzu.Segment.start_time:type_name -> google.protobuf.Duration
-	12, // 8: cobaltspeech.juzu.Segment.end_time:type_name -> google.protobuf.Duration
-	11, // 9: cobaltspeech.juzu.Segment.words:type_name -> cobaltspeech.juzu.WordInfo
-	12, // 10: cobaltspeech.juzu.WordInfo.start_time:type_name -> google.protobuf.Duration
-	12, // 11: cobaltspeech.juzu.WordInfo.duration:type_name -> google.protobuf.Duration
-	13, // 12: cobaltspeech.juzu.Juzu.Version:input_type -> google.protobuf.Empty
-	13, // 13: cobaltspeech.juzu.Juzu.ListModels:input_type -> google.protobuf.Empty
-	1,  // 14: cobaltspeech.juzu.Juzu.StreamingDiarize:input_type -> cobaltspeech.juzu.StreamingDiarizeRequest
-	2,  // 15: cobaltspeech.juzu.Juzu.Version:output_type -> cobaltspeech.juzu.VersionResponse
-	3,  // 16: cobaltspeech.juzu.Juzu.ListModels:output_type -> cobaltspeech.juzu.ListModelsResponse
-	6,  // 17: cobaltspeech.juzu.Juzu.StreamingDiarize:output_type -> cobaltspeech.juzu.DiarizationResponse
-	15, // [15:18] is the sub-list for method output_type
-	12, // [12:15] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	11, // 7: cobaltspeech.juzu.DiarizationResult.overlaps:type_name -> cobaltspeech.juzu.OverlapRegion
+	13, // 8: cobaltspeech.juzu.Segment.start_time:type_name -> google.protobuf.Duration
+	13, // 9: cobaltspeech.juzu.Segment.end_time:type_name -> google.protobuf.Duration
+	12, // 10: cobaltspeech.juzu.Segment.words:type_name -> cobaltspeech.juzu.WordInfo
+	13, // 11: cobaltspeech.juzu.OverlapRegion.start_time:type_name -> google.protobuf.Duration
+	13, // 12: cobaltspeech.juzu.OverlapRegion.end_time:type_name -> google.protobuf.Duration
+	13, // 13: cobaltspeech.juzu.WordInfo.start_time:type_name -> google.protobuf.Duration
+	13, // 14: cobaltspeech.juzu.WordInfo.duration:type_name -> google.protobuf.Duration
+	14, // 15: cobaltspeech.juzu.Juzu.Version:input_type -> google.protobuf.Empty
+	14, // 16: cobaltspeech.juzu.Juzu.ListModels:input_type -> google.protobuf.Empty
+	1,  // 17: cobaltspeech.juzu.Juzu.StreamingDiarize:input_type -> cobaltspeech.juzu.StreamingDiarizeRequest
+	2,  // 18: cobaltspeech.juzu.Juzu.Version:output_type -> cobaltspeech.juzu.VersionResponse
+	3,  // 19: cobaltspeech.juzu.Juzu.ListModels:output_type -> cobaltspeech.juzu.ListModelsResponse
+	6,  // 20: cobaltspeech.juzu.Juzu.StreamingDiarize:output_type -> cobaltspeech.juzu.DiarizationResponse
+	18, // [18:21] is the sub-list for method output_type
+	15, // [15:18] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
 }
 
 func init() { file_juzu_proto_init() }
@@ -1191,6 +1295,18 @@ func file_juzu_proto_init() {
 			}
 		}
 		file_juzu_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OverlapRegion); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_juzu_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*WordInfo); i {
 			case 0:
 				return &v.state
@@ -1213,7 +1329,7 @@ func file_juzu_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_juzu_proto_rawDesc,
 			NumEnums:      1,
-			NumMessages:   11,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
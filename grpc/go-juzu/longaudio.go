@@ -0,0 +1,521 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/assign"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const (
+	defaultLongAudioWindow      = 10 * time.Minute
+	defaultLongAudioOverlap     = 30 * time.Second
+	defaultLongAudioConcurrency = 4
+
+	// Words in the overlap of two windows are taken to be the same word if
+	// their text matches and they start within this tolerance.
+	wordMatchTolerance = 200 * time.Millisecond
+
+	// Weight, in seconds of overlap, that each matching word adds to the
+	// evidence that two speaker labels belong to the same speaker.
+	wordMatchWeight = 1.0
+)
+
+// LongAudioConfig configures how DiarizeLongAudio splits audio into windows.
+// Zero values select the defaults.
+type LongAudioConfig struct {
+	// Duration of each window; 10 minutes by default.
+	Window time.Duration
+
+	// Duration of the audio shared by consecutive windows; 30 seconds by
+	// default.  Speaker labels of consecutive windows are matched using
+	// this region, and it must be shorter than the window.
+	Overlap time.Duration
+
+	// Maximum number of windows diarized at the same time; 4 by default.
+	Concurrency int
+}
+
+// DiarizeLongAudio diarizes long recordings by splitting them into overlapping
+// windows, diarizing the windows concurrently with StreamingDiarize, and
+// stitching the results back into a single timeline.
+//
+// Juzu needs the whole audio before it returns results, so diarizing long
+// audio in one stream ties up the stream and much server memory for a long
+// time.  Windows instead are processed independently, and their speaker
+// labels are reconciled afterwards: labels of consecutive windows that cover
+// the same speech in the overlap region, and that agree on the words spoken
+// in it, are taken to belong to the same speaker.  Segments of each window are
+// used up to the middle of the overlap with the next window.  The speaker
+// labels of the result are "0", "1", ... in order of first appearance.
+//
+// A speaker who is silent during an overlap region cannot be matched across
+// it, and is given a new label when speaking again.  The overlap should
+// therefore be long enough for all active speakers to be heard in it.
+//
+// The audio, of the given size (bytes), is read from audioData.  Only the
+// RAW_LINEAR16 and WAV encodings of 16-bit mono audio are supported, and for
+// RAW_LINEAR16 the cfg must specify the sample rate.
+//
+// Only final results of each window are used.  CallOptions apply to the call
+// of every window.
+func (c *Client) DiarizeLongAudio(
+	ctx context.Context,
+	cfg *juzupb.DiarizationConfig,
+	audioData io.ReaderAt,
+	size int64,
+	lc LongAudioConfig,
+	opts ...CallOption,
+) (*juzupb.DiarizationResult, error) {
+
+	if lc.Window == 0 {
+		lc.Window = defaultLongAudioWindow
+	}
+	if lc.Overlap == 0 {
+		lc.Overlap = defaultLongAudioOverlap
+	}
+	if lc.Concurrency == 0 {
+		lc.Concurrency = defaultLongAudioConcurrency
+	}
+	if lc.Window < 0 || lc.Overlap < 0 || lc.Overlap >= lc.Window || lc.Concurrency < 0 {
+		return nil, fmt.Errorf("invalid long audio config %+v", lc)
+	}
+
+	src, err := newWindowSource(cfg, audioData, size)
+	if err != nil {
+		return nil, err
+	}
+
+	total := src.format.Duration(src.size)
+	if total == 0 {
+		return nil, fmt.Errorf("long audio diarization failed: no audio")
+	}
+	windows := planWindows(total, lc.Window, lc.Overlap)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The first window to fail cancels the others, and its error is the
+	// one reported rather than the cancellations that follow it.
+	var mu sync.Mutex
+	var firstErr error
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, lc.Concurrency)
+
+	for _, w := range windows {
+		wg.Add(1)
+		go func(w *window) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				fail(ctx.Err())
+				return
+			}
+
+			handler := func(resp *juzupb.DiarizationResponse) {
+				for _, r := range resp.Results {
+					if !r.GetIsPartial() {
+						w.segments = append(w.segments, r.GetSegments()...)
+						w.overlaps = w.overlaps || len(r.GetOverlaps()) > 0
+					}
+				}
+			}
+
+			if err := c.StreamingDiarize(ctx, src.cfg, src.reader(w), handler, opts...); err != nil {
+				fail(fmt.Errorf("window %v-%v: %v", w.start, w.end, err))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, fmt.Errorf("long audio diarization failed: %v", firstErr)
+	}
+
+	return stitchWindows(windows), nil
+}
+
+// windowSource reads windows of the audio given to DiarizeLongAudio.
+type windowSource struct {
+	cfg    *juzupb.DiarizationConfig // config sent for each window
+	audio  io.ReaderAt
+	format audio.Format
+	offset int64 // offset of the samples
+	size   int64 // size of the samples
+	wav    *audio.WAVHeader
+}
+
+func newWindowSource(cfg *juzupb.DiarizationConfig, r io.ReaderAt, size int64) (*windowSource, error) {
+	src := &windowSource{cfg: cfg, audio: r, size: size}
+
+	switch cfg.GetAudioEncoding() {
+	case juzupb.DiarizationConfig_RAW_LINEAR16:
+		if cfg.GetSampleRate() == 0 {
+			return nil, fmt.Errorf("sample rate is required for long audio diarization")
+		}
+		src.format = audio.Format{SampleRate: int(cfg.GetSampleRate()), Channels: 1}
+
+	case juzupb.DiarizationConfig_WAV:
+		h, err := audio.ReadWAVHeader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		if h.BitsPerSample != 16 || h.Channels != 1 {
+			return nil, fmt.Errorf("long audio diarization requires 16-bit mono audio")
+		}
+		src.wav = h
+		src.format = h.Format()
+		src.offset = h.DataOffset
+		src.size = size - h.DataOffset
+		if h.DataSize < src.size {
+			src.size = h.DataSize
+		}
+
+	default:
+		return nil, fmt.Errorf("long audio diarization is not supported for %v audio", cfg.GetAudioEncoding())
+	}
+
+	return src, nil
+}
+
+// reader returns a reader for the audio of the given window.  WAV windows get
+// a header of their own.
+func (src *windowSource) reader(w *window) io.Reader {
+	start := src.format.Offset(w.start)
+	end := src.format.Offset(w.end)
+	if end > src.size || w.end >= src.format.Duration(src.size) {
+		// the last window reaches the end of the audio
+		end = src.size
+	}
+
+	r := io.NewSectionReader(src.audio, src.offset+start, end-start)
+	if src.wav == nil {
+		return r
+	}
+
+	h := *src.wav
+	h.DataSize = end - start
+	return io.MultiReader(bytes.NewReader(h.Bytes()), r)
+}
+
+// window is a portion of the audio diarized on its own.
+type window struct {
+	start, end time.Duration
+
+	// final segments, with times relative to the start of the window
+	segments []*juzupb.Segment
+
+	// whether the server marked overlapping speech in the window
+	overlaps bool
+}
+
+// planWindows splits audio of the given duration into windows of the given
+// length, each overlapping the previous one by the given overlap.
+func planWindows(total, length, overlap time.Duration) []*window {
+	var windows []*window
+	for start := time.Duration(0); ; start += length - overlap {
+		end := start + length
+		if end >= total {
+			windows = append(windows, &window{start: start, end: total})
+			return windows
+		}
+		windows = append(windows, &window{start: start, end: end})
+	}
+}
+
+// stitchWindows joins the segments of all windows into one result.
+func stitchWindows(windows []*window) *juzupb.DiarizationResult {
+	// move all segments to the global timeline
+	for _, w := range windows {
+		for i, seg := range w.segments {
+			w.segments[i] = offsetSegment(seg, w.start)
+		}
+	}
+
+	// Give each speaker a global id.  The labels of each window are
+	// matched against those of the previous window, and labels that cannot
+	// be matched belong to new speakers.
+	ids := make([]map[string]int, len(windows))
+	numSpeakers := 0
+	for k, w := range windows {
+		ids[k] = make(map[string]int)
+		labels := segmentLabels(w.segments)
+
+		if k > 0 {
+			prev := windows[k-1]
+			prevLabels := segmentLabels(prev.segments)
+			weights := overlapWeights(w.segments, prev.segments, labels, prevLabels, w.start, prev.end)
+			for i, j := range assign.Maximize(weights) {
+				if j >= 0 && weights[i][j] > 0 {
+					ids[k][labels[i]] = ids[k-1][prevLabels[j]]
+				}
+			}
+		}
+
+		for _, l := range labels {
+			if _, ok := ids[k][l]; !ok {
+				ids[k][l] = numSpeakers
+				numSpeakers++
+			}
+		}
+	}
+
+	// Use each window up to the middle of its overlap with the next.
+	var segs []*juzupb.Segment
+	var from []int // window of each segment
+	for k, w := range windows {
+		lo, hi := time.Duration(-1), time.Duration(-1)
+		if k > 0 {
+			lo = (w.start + windows[k-1].end) / 2
+		}
+		if k < len(windows)-1 {
+			hi = (windows[k+1].start + w.end) / 2
+		}
+
+		for _, seg := range w.segments {
+			if s := clipSegment(seg, lo, hi); s != nil {
+				s.SpeakerLabel = strconv.Itoa(ids[k][seg.SpeakerLabel])
+				segs = append(segs, s)
+				from = append(from, k)
+			}
+		}
+	}
+
+	// Each window contributes a separate stretch of time, so sorting the
+	// segments of each window sorts them all.
+	for k := 0; k < len(windows); k++ {
+		first := sort.SearchInts(from, k)
+		last := sort.SearchInts(from, k+1)
+		sort.SliceStable(segs[first:last], func(i, j int) bool {
+			return segs[first+i].StartTime.AsDuration() < segs[first+j].StartTime.AsDuration()
+		})
+	}
+
+	// A segment that crosses the cut between two windows was split in two;
+	// join the pieces again.
+	var result []*juzupb.Segment
+	for i, seg := range segs {
+		if n := len(result); n > 0 && from[i] != from[i-1] {
+			last := result[n-1]
+			if last.SpeakerLabel == seg.SpeakerLabel &&
+				seg.StartTime.AsDuration()-last.EndTime.AsDuration() <= 10*time.Millisecond {
+				last.EndTime = seg.EndTime
+				last.Words = append(last.Words, seg.Words...)
+				last.Transcript = strings.TrimSpace(last.Transcript + " " + seg.Transcript)
+				continue
+			}
+		}
+		result = append(result, seg)
+	}
+
+	// Relabel speakers in order of first appearance.
+	names := make(map[string]string)
+	var labels []string
+	for _, seg := range result {
+		name, ok := names[seg.SpeakerLabel]
+		if !ok {
+			name = strconv.Itoa(len(labels))
+			names[seg.SpeakerLabel] = name
+			labels = append(labels, name)
+		}
+		seg.SpeakerLabel = name
+	}
+
+	// Overlapping speech marked in the windows is found again from the
+	// stitched segments, which hold the global speaker labels.
+	stitched := &juzupb.DiarizationResult{Segments: result, SpeakerLabels: labels}
+	for _, w := range windows {
+		if w.overlaps {
+			stitched.Overlaps = Overlaps(result)
+			break
+		}
+	}
+	return stitched
+}
+
+// offsetSegment returns a copy of the segment with all times moved by d.
+func offsetSegment(seg *juzupb.Segment, d time.Duration) *juzupb.Segment {
+	s := proto.Clone(seg).(*juzupb.Segment)
+	s.StartTime = durationpb.New(s.StartTime.AsDuration() + d)
+	s.EndTime = durationpb.New(s.EndTime.AsDuration() + d)
+	for _, w := range s.Words {
+		w.StartTime = durationpb.New(w.StartTime.AsDuration() + d)
+	}
+	return s
+}
+
+// clipSegment returns the part of the segment within [lo, hi), or nil if there
+// is none.  A negative bound is no bound.  Words are kept if they start within
+// the range, and the transcript is rebuilt from them.  Segments without words
+// keep their transcript in the part holding their midpoint.
+func clipSegment(seg *juzupb.Segment, lo, hi time.Duration) *juzupb.Segment {
+	start, end := seg.StartTime.AsDuration(), seg.EndTime.AsDuration()
+	in := func(t time.Duration) bool {
+		return (lo < 0 || t >= lo) && (hi < 0 || t < hi)
+	}
+
+	s := proto.Clone(seg).(*juzupb.Segment)
+	if lo >= 0 && start < lo {
+		start = lo
+	}
+	if hi >= 0 && end > hi {
+		end = hi
+	}
+	if end <= start {
+		return nil
+	}
+	s.StartTime = durationpb.New(start)
+	s.EndTime = durationpb.New(end)
+
+	if len(seg.Words) == 0 {
+		if !in((seg.StartTime.AsDuration() + seg.EndTime.AsDuration()) / 2) {
+			s.Transcript = ""
+		}
+		return s
+	}
+
+	s.Words = s.Words[:0]
+	var text []string
+	for _, w := range seg.Words {
+		if in(w.StartTime.AsDuration()) {
+			s.Words = append(s.Words, proto.Clone(w).(*juzupb.WordInfo))
+			text = append(text, w.Word)
+		}
+	}
+	if len(s.Words) != len(seg.Words) {
+		s.Transcript = strings.Join(text, " ")
+	}
+	return s
+}
+
+// segmentLabels returns the distinct speaker labels of the segments in order
+// of first appearance.
+func segmentLabels(segs []*juzupb.Segment) []string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, s := range segs {
+		if !seen[s.SpeakerLabel] {
+			seen[s.SpeakerLabel] = true
+			labels = append(labels, s.SpeakerLabel)
+		}
+	}
+	return labels
+}
+
+// overlapWeights returns, for each pair of labels of segs and of prev, the
+// evidence that they belong to the same speaker, based on the region [from,
+// to) covered by both.  The evidence is the time during which both labels are
+// active, plus a fixed weight for each word that both recognized.
+func overlapWeights(segs, prev []*juzupb.Segment, labels, prevLabels []string,
+	from, to time.Duration) [][]float64 {
+
+	index := func(labels []string) map[string]int {
+		m := make(map[string]int, len(labels))
+		for i, l := range labels {
+			m[l] = i
+		}
+		return m
+	}
+	row, col := index(labels), index(prevLabels)
+
+	w := make([][]float64, len(labels))
+	for i := range w {
+		w[i] = make([]float64, len(prevLabels))
+	}
+
+	for _, a := range segs {
+		for _, b := range prev {
+			start := maxDuration(from, a.StartTime.AsDuration(), b.StartTime.AsDuration())
+			end := minDuration(to, a.EndTime.AsDuration(), b.EndTime.AsDuration())
+			if end <= start {
+				continue
+			}
+
+			i, j := row[a.SpeakerLabel], col[b.SpeakerLabel]
+			w[i][j] += (end - start).Seconds()
+
+			for _, wa := range a.Words {
+				for _, wb := range b.Words {
+					ta, tb := wa.StartTime.AsDuration(), wb.StartTime.AsDuration()
+					if ta < start || ta >= end || tb < start || tb >= end {
+						continue
+					}
+					d := ta - tb
+					if d < 0 {
+						d = -d
+					}
+					if d <= wordMatchTolerance && normalizeWord(wa.Word) == normalizeWord(wb.Word) {
+						w[i][j] += wordMatchWeight
+					}
+				}
+			}
+		}
+	}
+	return w
+}
+
+// normalizeWord lower cases the word and strips punctuation around it, so
+// that formatting differences do not keep words from matching.
+func normalizeWord(w string) string {
+	return strings.ToLower(strings.TrimFunc(w, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	}))
+}
+
+func minDuration(ds ...time.Duration) time.Duration {
+	m := ds[0]
+	for _, d := range ds[1:] {
+		if d < m {
+			m = d
+		}
+	}
+	return m
+}
+
+func maxDuration(ds ...time.Duration) time.Duration {
+	m := ds[0]
+	for _, d := range ds[1:] {
+		if d > m {
+			m = d
+		}
+	}
+	return m
+}
@@ -0,0 +1,217 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// The test audio for long audio diarization has speakers 1 and 2 taking turns
+// of 1.5s, except for turn 4 which is silent.  Each sample holds the speaker
+// (times 1000) plus the index of the 0.5s word it belongs to, which lets the
+// mock server diarize and transcribe it.
+
+const (
+	longAudioRate     = 8000
+	longAudioDuration = 20 * time.Second
+	longAudioTurn     = 1500 * time.Millisecond
+	longAudioWord     = 500 * time.Millisecond
+)
+
+func longAudioSamples() []byte {
+	n := int(longAudioDuration.Seconds() * longAudioRate)
+	b := make([]byte, 2*n)
+	for i := 0; i < n; i++ {
+		t := time.Duration(i) * time.Second / longAudioRate
+		turn := int(t / longAudioTurn)
+		if turn == 4 {
+			continue
+		}
+		v := (1+turn%2)*1000 + int(t/longAudioWord)
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+// mockCalls counts the calls to mockDiarize, which uses different speaker
+// labels on each call, just like independent calls to a server could.
+var mockCalls int32
+
+// mockDiarize diarizes audio made by longAudioSamples.
+func mockDiarize(cfg *juzupb.DiarizationConfig, data []byte) (*juzupb.DiarizationResponse, error) {
+	if cfg.GetAudioEncoding() == juzupb.DiarizationConfig_WAV {
+		h, err := audio.ReadWAVHeader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		data = data[h.DataOffset:]
+	}
+
+	call := int(atomic.AddInt32(&mockCalls, 1))
+	at := func(i int) *durationpb.Duration {
+		return durationpb.New(time.Duration(i) * time.Second / longAudioRate)
+	}
+
+	var segs []*juzupb.Segment
+	var seg *juzupb.Segment
+	prev := 0
+	for i := 0; i <= len(data)/2; i++ {
+		v := 0
+		if i < len(data)/2 {
+			v = int(binary.LittleEndian.Uint16(data[2*i:]))
+		}
+		if v == prev {
+			continue
+		}
+
+		if seg != nil {
+			seg.Words[len(seg.Words)-1].Duration = durationpb.New(at(i).AsDuration() -
+				seg.Words[len(seg.Words)-1].StartTime.AsDuration())
+			if v/1000 != prev/1000 {
+				seg.EndTime = at(i)
+				segs = append(segs, seg)
+				seg = nil
+			}
+		}
+
+		if v != 0 {
+			if seg == nil {
+				seg = &juzupb.Segment{
+					SpeakerLabel: fmt.Sprintf("spk%d", (v/1000+call)%2),
+					StartTime:    at(i),
+				}
+			}
+			word := fmt.Sprintf("w%d", v%1000)
+			seg.Words = append(seg.Words, &juzupb.WordInfo{Word: word, StartTime: at(i), Confidence: 1})
+			seg.Transcript = strings.TrimSpace(seg.Transcript + " " + word)
+		}
+		prev = v
+	}
+
+	return &juzupb.DiarizationResponse{
+		Results: []*juzupb.DiarizationResult{{Segments: segs}},
+	}, nil
+}
+
+func TestDiarizeLongAudio(t *testing.T) {
+	svr, port, err := setupGRPCServer()
+	defer svr.Stop()
+
+	if err != nil {
+		t.Fatalf("could not set up testing server: %v", err)
+	}
+
+	c, err := juzu.NewClient(fmt.Sprintf("localhost:%d", port), juzu.WithInsecure())
+	if err != nil {
+		t.Fatalf("could not create client: %v", err)
+	}
+	defer c.Close()
+
+	// Expected segments are all turns with speech, and the speakers are
+	// labelled in order of appearance.
+	type segment struct {
+		label      string
+		start, end time.Duration
+		transcript string
+	}
+	var want []segment
+	for turn := 0; turn < int(longAudioDuration/longAudioTurn)+1; turn++ {
+		start := time.Duration(turn) * longAudioTurn
+		if turn == 4 || start >= longAudioDuration {
+			continue
+		}
+		end := start + longAudioTurn
+		if end > longAudioDuration {
+			end = longAudioDuration
+		}
+		var words []string
+		for w := start; w < end; w += longAudioWord {
+			words = append(words, fmt.Sprintf("w%d", w/longAudioWord))
+		}
+		want = append(want, segment{fmt.Sprint(turn % 2), start, end, strings.Join(words, " ")})
+	}
+
+	// Windows of 6s with 2s overlap cut the audio at 5s, 9s, 13s and 17s,
+	// and the turns at 4.5s, 12s and 16.5s cross those cuts.
+	lc := juzu.LongAudioConfig{Window: 6 * time.Second, Overlap: 2 * time.Second, Concurrency: 2}
+
+	samples := longAudioSamples()
+	h := audio.WAVHeader{SampleRate: longAudioRate, Channels: 1, BitsPerSample: 16, DataSize: int64(len(samples))}
+	wav := append(h.Bytes(), samples...)
+
+	for _, tc := range []struct {
+		name string
+		cfg  *juzupb.DiarizationConfig
+		data []byte
+	}{
+		{"raw", &juzupb.DiarizationConfig{ModelId: "test-long", SampleRate: longAudioRate}, samples},
+		{"wav", &juzupb.DiarizationConfig{ModelId: "test-long", AudioEncoding: juzupb.DiarizationConfig_WAV}, wav},
+	} {
+		res, err := c.DiarizeLongAudio(context.Background(), tc.cfg, bytes.NewReader(tc.data), int64(len(tc.data)), lc)
+		if err != nil {
+			t.Errorf("%s: did not expect error in long audio diarization; got %v", tc.name, err)
+			continue
+		}
+
+		var got []segment
+		for _, s := range res.Segments {
+			got = append(got, segment{s.SpeakerLabel, s.StartTime.AsDuration(), s.EndTime.AsDuration(), s.Transcript})
+			if len(s.Words) != len(strings.Fields(s.Transcript)) {
+				t.Errorf("%s: segment %v has %d words for transcript %q", tc.name, s.StartTime.AsDuration(),
+					len(s.Words), s.Transcript)
+			}
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: long audio diarization\ngot  %v\nwant %v", tc.name, got, want)
+		}
+
+		if fmt.Sprint(res.SpeakerLabels) != "[0 1]" {
+			t.Errorf("%s: got speaker labels %v; want [0 1]", tc.name, res.SpeakerLabels)
+		}
+	}
+
+	// invalid configurations
+	_, err = c.DiarizeLongAudio(context.Background(), &juzupb.DiarizationConfig{ModelId: "test-long"},
+		bytes.NewReader(samples), int64(len(samples)), lc)
+	if err == nil {
+		t.Errorf("long audio diarization without sample rate: want error, got nil")
+	}
+
+	_, err = c.DiarizeLongAudio(context.Background(), &juzupb.DiarizationConfig{ModelId: "test-long", SampleRate: longAudioRate},
+		bytes.NewReader(samples), int64(len(samples)), juzu.LongAudioConfig{Window: time.Second, Overlap: time.Second})
+	if err == nil {
+		t.Errorf("long audio diarization with overlap as long as the window: want error, got nil")
+	}
+
+	_, err = c.DiarizeLongAudio(context.Background(),
+		&juzupb.DiarizationConfig{ModelId: "test-long", AudioEncoding: juzupb.DiarizationConfig_MP3},
+		bytes.NewReader(samples), int64(len(samples)), lc)
+	if err == nil {
+		t.Errorf("long audio diarization of MP3 audio: want error, got nil")
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu
+
+import (
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/overlap"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Overlaps returns the regions where segments of different speakers overlap
+// in time, in order.  Each region holds the labels of its speakers, sorted,
+// and adjacent regions have different speakers.
+func Overlaps(segments []*juzupb.Segment) []*juzupb.OverlapRegion {
+	return overlap.Find(segments)
+}
+
+// ResultOverlaps returns the overlapping speech regions of the result: those
+// marked by the server if there are any, or else those found from the
+// segments by Overlaps.
+func ResultOverlaps(r *juzupb.DiarizationResult) []*juzupb.OverlapRegion {
+	if len(r.Overlaps) > 0 {
+		return r.Overlaps
+	}
+	return Overlaps(r.Segments)
+}
@@ -0,0 +1,68 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func describeOverlaps(regions []*juzupb.OverlapRegion) []string {
+	var desc []string
+	for _, r := range regions {
+		desc = append(desc, fmt.Sprintf("%v-%v %s", r.StartTime.AsDuration().Seconds(),
+			r.EndTime.AsDuration().Seconds(), strings.Join(r.SpeakerLabels, ",")))
+	}
+	return desc
+}
+
+func TestOverlaps(t *testing.T) {
+	segs := []*juzupb.Segment{
+		segment("1", 0, 10*time.Second, ""),
+		segment("2", 2*time.Second, 4*time.Second, ""),
+		segment("3", 3*time.Second, 5*time.Second, ""),
+		segment("2", 4*time.Second, 6*time.Second, ""), // same speakers as before
+		segment("1", 10*time.Second, 12*time.Second, ""),
+		segment("2", 12*time.Second, 14*time.Second, ""), // touching is not overlapping
+		segment("1", 12*time.Second, 11*time.Second, ""), // empty
+		segment("3", 13*time.Second, 15*time.Second, ""),
+	}
+	want := []string{"2-3 1,2", "3-5 1,2,3", "5-6 1,2", "13-14 2,3"}
+	if got := describeOverlaps(juzu.Overlaps(segs)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Overlaps = %q; want %q", got, want)
+	}
+
+	// overlaps marked by the server are used as they are
+	marked := []*juzupb.OverlapRegion{{
+		StartTime:     durationpb.New(2500 * time.Millisecond),
+		EndTime:       durationpb.New(3 * time.Second),
+		SpeakerLabels: []string{"1", "2"},
+	}}
+	r := &juzupb.DiarizationResult{Segments: segs, Overlaps: marked}
+	if got := juzu.ResultOverlaps(r); !reflect.DeepEqual(got, marked) {
+		t.Errorf("ResultOverlaps = %q; want marked %q", describeOverlaps(got), describeOverlaps(marked))
+	}
+	r.Overlaps = nil
+	if got := describeOverlaps(juzu.ResultOverlaps(r)); !reflect.DeepEqual(got, want) {
+		t.Errorf("ResultOverlaps = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package postprocess cleans up diarization segments: it merges consecutive
+// segments of the same speaker, and absorbs very short segments, which are
+// most often spurious speaker changes, into their neighbours.
+//
+// Segments are processed in order of start time.  The functions do not modify
+// the segments given to them: joined segments are new, and the others are
+// returned as they are.  When segments are joined, their words are kept in
+// time order and their transcripts are joined with spaces, so that the
+// transcript and words of the result stay consistent.
+package postprocess
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/overlap"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Options configures Process.
+type Options struct {
+	// MaxGap is the longest gap between consecutive segments of the same
+	// speaker for them to be merged.  Segments that touch or overlap are
+	// always merged.
+	MaxGap time.Duration
+
+	// MinDuration is the duration below which segments are absorbed into
+	// their neighbours.  Zero keeps all segments.
+	MinDuration time.Duration
+}
+
+// Process merges the segments, absorbs the short ones, and merges the result
+// again, with the given options.
+func Process(segments []*juzupb.Segment, opts Options) []*juzupb.Segment {
+	segs := Merge(segments, opts.MaxGap)
+	if opts.MinDuration > 0 {
+		segs = Merge(Absorb(segs, opts.MinDuration), opts.MaxGap)
+	}
+	return segs
+}
+
+// ProcessResult returns a copy of the result with its segments processed.
+// Speaker labels that no longer have segments are dropped from its labels.
+// If the result has overlapping speech regions, they are found again from the
+// processed segments, as merged and absorbed segments no longer match them.
+func ProcessResult(r *juzupb.DiarizationResult, opts Options) *juzupb.DiarizationResult {
+	segs := Process(r.Segments, opts)
+
+	spoken := make(map[string]bool)
+	for _, s := range segs {
+		spoken[s.SpeakerLabel] = true
+	}
+	var labels []string
+	for _, l := range r.SpeakerLabels {
+		if spoken[l] {
+			labels = append(labels, l)
+		}
+	}
+
+	out := &juzupb.DiarizationResult{Segments: segs, SpeakerLabels: labels, IsPartial: r.IsPartial}
+	if len(r.Overlaps) > 0 {
+		out.Overlaps = overlap.Find(segs)
+	}
+	return out
+}
+
+// Merge joins consecutive segments of the same speaker that are separated by
+// at most maxGap.
+func Merge(segments []*juzupb.Segment, maxGap time.Duration) []*juzupb.Segment {
+	var segs []*juzupb.Segment
+	for _, s := range sorted(segments) {
+		if n := len(segs); n > 0 {
+			last := segs[n-1]
+			if last.SpeakerLabel == s.SpeakerLabel && start(s)-end(last) <= maxGap {
+				segs[n-1] = join(last, s, last.SpeakerLabel)
+				continue
+			}
+		}
+		segs = append(segs, s)
+	}
+	return segs
+}
+
+// Absorb joins each segment shorter than minDuration to one of its
+// neighbours, taking the label of that neighbour, starting with the shortest
+// segment.  A short segment between two segments of the same speaker is
+// joined to both.  Otherwise it goes to the nearest neighbour, or the longest
+// if both are as near.  A single segment is always kept.
+func Absorb(segments []*juzupb.Segment, minDuration time.Duration) []*juzupb.Segment {
+	segs := sorted(segments)
+	for len(segs) > 1 {
+		i := -1
+		for j, s := range segs {
+			if d := duration(s); d < minDuration && (i < 0 || d < duration(segs[i])) {
+				i = j
+			}
+		}
+		if i < 0 {
+			break
+		}
+
+		short := segs[i]
+		switch {
+		case i == 0:
+			segs[1] = join(short, segs[1], segs[1].SpeakerLabel)
+		case i == len(segs)-1:
+			segs[i-1] = join(segs[i-1], short, segs[i-1].SpeakerLabel)
+		case segs[i-1].SpeakerLabel == segs[i+1].SpeakerLabel:
+			label := segs[i-1].SpeakerLabel
+			segs[i+1] = join(join(segs[i-1], short, label), segs[i+1], label)
+			segs = append(segs[:i-1], segs[i:]...)
+			i--
+		case nearerPrevious(segs[i-1], short, segs[i+1]):
+			segs[i-1] = join(segs[i-1], short, segs[i-1].SpeakerLabel)
+		default:
+			segs[i+1] = join(short, segs[i+1], segs[i+1].SpeakerLabel)
+		}
+		segs = append(segs[:i], segs[i+1:]...)
+	}
+	return segs
+}
+
+// nearerPrevious tells whether the segment s should be joined to the
+// previous segment rather than the next one.
+func nearerPrevious(prev, s, next *juzupb.Segment) bool {
+	gapPrev, gapNext := start(s)-end(prev), start(next)-end(s)
+	if gapPrev != gapNext {
+		return gapPrev < gapNext
+	}
+	return duration(prev) >= duration(next)
+}
+
+// join returns a segment with the given label covering both segments, where a
+// does not start after b.
+func join(a, b *juzupb.Segment, label string) *juzupb.Segment {
+	s := &juzupb.Segment{
+		SpeakerLabel: label,
+		StartTime:    durationpb.New(start(a)),
+		EndTime:      durationpb.New(maxDuration(end(a), end(b))),
+	}
+
+	// Words are sorted by time, as segments may overlap, and the transcript
+	// is then rebuilt in the same order.  The text of each word is taken
+	// from the transcript of its segment when they have as many words, to
+	// keep its formatting.
+	type word struct {
+		info *juzupb.WordInfo
+		text string
+	}
+	var words []word
+	for _, seg := range []*juzupb.Segment{a, b} {
+		text := strings.Fields(seg.Transcript)
+		for i, w := range seg.Words {
+			wd := word{proto.Clone(w).(*juzupb.WordInfo), w.Word}
+			if len(text) == len(seg.Words) {
+				wd.text = text[i]
+			}
+			words = append(words, wd)
+		}
+	}
+	sort.SliceStable(words, func(i, j int) bool {
+		return words[i].info.StartTime.AsDuration() < words[j].info.StartTime.AsDuration()
+	})
+
+	var text []string
+	if len(words) > 0 {
+		for _, w := range words {
+			s.Words = append(s.Words, w.info)
+			text = append(text, w.text)
+		}
+	} else {
+		for _, t := range []string{a.Transcript, b.Transcript} {
+			if t != "" {
+				text = append(text, t)
+			}
+		}
+	}
+	s.Transcript = strings.Join(text, " ")
+	return s
+}
+
+// sorted returns the segments sorted by start time.
+func sorted(segments []*juzupb.Segment) []*juzupb.Segment {
+	segs := append([]*juzupb.Segment(nil), segments...)
+	sort.SliceStable(segs, func(i, j int) bool { return start(segs[i]) < start(segs[j]) })
+	return segs
+}
+
+func start(s *juzupb.Segment) time.Duration {
+	return s.StartTime.AsDuration()
+}
+
+func end(s *juzupb.Segment) time.Duration {
+	return s.EndTime.AsDuration()
+}
+
+func duration(s *juzupb.Segment) time.Duration {
+	return end(s) - start(s)
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,167 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package postprocess_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/postprocess"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// segment returns a segment from start to end, in seconds, with its words
+// shared out evenly.
+func segment(label string, start, end float64, transcript string) *juzupb.Segment {
+	sec := func(f float64) time.Duration { return time.Duration(f * float64(time.Second)) }
+	s := &juzupb.Segment{
+		SpeakerLabel: label,
+		StartTime:    durationpb.New(sec(start)),
+		EndTime:      durationpb.New(sec(end)),
+		Transcript:   transcript,
+	}
+	words := strings.Fields(transcript)
+	for i, w := range words {
+		d := (end - start) / float64(len(words))
+		s.Words = append(s.Words, &juzupb.WordInfo{
+			Word:      w,
+			StartTime: durationpb.New(sec(start + float64(i)*d)),
+			Duration:  durationpb.New(sec(d)),
+		})
+	}
+	return s
+}
+
+// describe returns a description of each segment, checking that its words
+// match its transcript.
+func describe(t *testing.T, segs []*juzupb.Segment) []string {
+	var desc []string
+	for _, s := range segs {
+		var words []string
+		for _, w := range s.Words {
+			words = append(words, w.Word)
+		}
+		if strings.Join(words, " ") != s.Transcript {
+			t.Errorf("segment %v has words %q", s, words)
+		}
+		desc = append(desc, fmt.Sprintf("%s %v-%v %s", s.SpeakerLabel,
+			s.StartTime.AsDuration().Seconds(), s.EndTime.AsDuration().Seconds(), s.Transcript))
+	}
+	return desc
+}
+
+func TestMerge(t *testing.T) {
+	segs := []*juzupb.Segment{
+		segment("A", 2.2, 3, "d"),
+		segment("A", 0, 1, "a b"),
+		segment("A", 1.1, 2, "c"),
+		segment("B", 3, 4, "e"),
+		segment("A", 4, 5, "f"),
+	}
+	want := []string{"A 0-3 a b c d", "B 3-4 e", "A 4-5 f"}
+	if got := describe(t, postprocess.Merge(segs, 200*time.Millisecond)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge = %q; want %q", got, want)
+	}
+	if segs[0].EndTime.AsDuration() != 3*time.Second || len(segs[1].Words) != 2 {
+		t.Errorf("Merge modified its input")
+	}
+
+	want = []string{"A 0-1 a b", "A 1.1-2 c", "A 2.2-3 d", "B 3-4 e", "A 4-5 f"}
+	if got := describe(t, postprocess.Merge(segs, 0)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge with no gap = %q; want %q", got, want)
+	}
+
+	// words of overlapping segments are interleaved in the transcript
+	segs = []*juzupb.Segment{segment("A", 0, 2, "a c"), segment("A", 0.5, 1.5, "b")}
+	want = []string{"A 0-2 a b c"}
+	if got := describe(t, postprocess.Merge(segs, 0)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge of overlapping segments = %q; want %q", got, want)
+	}
+}
+
+func TestAbsorb(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		segs []*juzupb.Segment
+		want []string
+	}{
+		{
+			"flip",
+			[]*juzupb.Segment{segment("A", 0, 2, "a"), segment("B", 2, 2.3, "b"), segment("A", 2.3, 4, "c")},
+			[]string{"A 0-4 a b c"},
+		},
+		{
+			"nearest",
+			[]*juzupb.Segment{segment("A", 0, 2, "a"), segment("B", 2.5, 2.8, "b"), segment("C", 2.8, 4, "c")},
+			[]string{"A 0-2 a", "C 2.5-4 b c"},
+		},
+		{
+			"longest",
+			[]*juzupb.Segment{segment("A", 0, 2, "a"), segment("B", 2, 2.3, "b"), segment("C", 2.3, 3, "c")},
+			[]string{"A 0-2.3 a b", "C 2.3-3 c"},
+		},
+		{
+			"shortest first",
+			[]*juzupb.Segment{segment("A", 0, 0.4, "a"), segment("B", 0.4, 0.5, "b"), segment("C", 0.5, 3, "c")},
+			[]string{"C 0-3 a b c"},
+		},
+		{
+			"single",
+			[]*juzupb.Segment{segment("A", 0, 0.1, "a")},
+			[]string{"A 0-0.1 a"},
+		},
+	} {
+		if got := describe(t, postprocess.Absorb(tc.segs, 500*time.Millisecond)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Absorb = %q; want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestProcessResult(t *testing.T) {
+	r := &juzupb.DiarizationResult{
+		Segments: []*juzupb.Segment{
+			segment("A", 0, 2, "a"),
+			segment("A", 2.1, 3, "b"),
+			segment("B", 3, 3.2, "c"),
+			segment("A", 3.2, 5, "d"),
+			segment("B", 4.5, 6, "e"),
+			segment("B", 6, 8, "f"),
+		},
+		SpeakerLabels: []string{"A", "B", "C"},
+		Overlaps: []*juzupb.OverlapRegion{{
+			StartTime:     durationpb.New(3 * time.Second),
+			EndTime:       durationpb.New(3200 * time.Millisecond),
+			SpeakerLabels: []string{"A", "B"},
+		}},
+	}
+	got := postprocess.ProcessResult(r, postprocess.Options{MaxGap: 250 * time.Millisecond, MinDuration: time.Second})
+	if want := []string{"A 0-5 a b c d", "B 4.5-8 e f"}; !reflect.DeepEqual(describe(t, got.Segments), want) {
+		t.Errorf("segments are %q; want %q", describe(t, got.Segments), want)
+	}
+	if want := []string{"A", "B"}; !reflect.DeepEqual(got.SpeakerLabels, want) {
+		t.Errorf("speaker labels are %q; want %q", got.SpeakerLabels, want)
+	}
+
+	// the overlap of the absorbed segment is gone, and the one of the
+	// processed segments is found
+	if len(got.Overlaps) != 1 || got.Overlaps[0].StartTime.AsDuration() != 4500*time.Millisecond ||
+		got.Overlaps[0].EndTime.AsDuration() != 5*time.Second {
+		t.Errorf("overlaps are %v; want A and B at 4.5-5s", got.Overlaps)
+	}
+}
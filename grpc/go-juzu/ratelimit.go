@@ -0,0 +1,78 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the number of bytes per second that may be sent by one or
+// more concurrent senders.
+//
+// Each call to wait reserves the next available slot on a shared timeline, and
+// senders are therefore served in the order in which they asked.  Since every
+// stream reserves one message at a time, concurrent streams end up taking
+// turns and share the available bandwidth fairly.
+type rateLimiter struct {
+	bytesPerSec float64
+
+	mu   sync.Mutex
+	next time.Time // earliest time at which the next reservation may start
+}
+
+func newRateLimiter(bytesPerSec uint32) *rateLimiter {
+	return &rateLimiter{bytesPerSec: float64(bytesPerSec)}
+}
+
+// wait blocks until n bytes may be sent, or until the context is done.  If the
+// context is done first, the reservation is released, as long as no other
+// sender has reserved a slot after it.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		// The limiter was idle; do not let unused bandwidth accumulate
+		// into a burst.
+		start = now
+	}
+	end := start.Add(time.Duration(float64(n) / l.bytesPerSec * float64(time.Second)))
+	l.next = end
+	l.mu.Unlock()
+
+	d := start.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if l.next.Equal(end) {
+			// Nobody queued after us, so the bytes we will not send
+			// do not delay later senders.
+			l.next = start
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
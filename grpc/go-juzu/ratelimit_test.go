@@ -0,0 +1,75 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := newRateLimiter(1000)
+
+	// the first second is reserved right away, and the second one needs to
+	// wait for it
+	if err := l.wait(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	reserved := l.reservedUntil()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx, 1000); err != context.DeadlineExceeded {
+		t.Fatalf("wait returned %v; want %v", err, context.DeadlineExceeded)
+	}
+	if got := l.reservedUntil(); !got.Equal(reserved) {
+		t.Errorf("cancelled wait left the limiter reserved until %v; want %v", got, reserved)
+	}
+
+	// a cancelled wait that others queued after keeps its slot, since
+	// their slots start after it
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	done1, done2 := make(chan error), make(chan error)
+	go func() { done1 <- l.wait(ctx1, 1000) }()
+	waitQueued(l, reserved)
+	first := l.reservedUntil()
+	go func() { done2 <- l.wait(ctx2, 1000) }()
+	waitQueued(l, first)
+	tail := l.reservedUntil()
+
+	cancel1()
+	if err := <-done1; err != context.Canceled {
+		t.Fatalf("wait returned %v; want %v", err, context.Canceled)
+	}
+	if got := l.reservedUntil(); !got.Equal(tail) {
+		t.Errorf("cancelled wait left the limiter reserved until %v; want %v", got, tail)
+	}
+}
+
+func (l *rateLimiter) reservedUntil() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next
+}
+
+// waitQueued waits until a new reservation is made after the given time.
+func waitQueued(l *rateLimiter, after time.Time) {
+	for l.reservedUntil().Equal(after) {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,347 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redact removes personal information, such as card numbers, phone
+// numbers and email addresses, from diarized transcripts, and tells where it
+// was spoken in the audio so that it can be bleeped out.
+//
+// Detectors run over the transcript of each segment, and separately over its
+// words, in which numbers are often spelled out one digit per word, as in
+// "four one one one".  Digits spoken as separate words are joined before
+// matching, so that the patterns of detectors can be written for digits.
+package redact
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Detector finds one kind of sensitive information.
+type Detector struct {
+	// Name of the kind of information, used in the tokens that replace
+	// it.
+	Name string
+
+	// Pattern matches candidate text.
+	Pattern *regexp.Regexp
+
+	// Validate, if set, tells whether a match of the pattern is to be
+	// redacted.
+	Validate func(match string) bool
+}
+
+// Detectors of common personal information.
+var (
+	// CardNumber finds payment card numbers of 13 to 19 digits, which may
+	// be grouped with spaces or dashes, and that pass the Luhn check.
+	CardNumber = &Detector{
+		Name:     "CARD_NUMBER",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: luhn,
+	}
+
+	// PhoneNumber finds phone numbers of the North American format, with
+	// an optional country code.
+	PhoneNumber = &Detector{
+		Name:    "PHONE_NUMBER",
+		Pattern: regexp.MustCompile(`(?:\+?\b\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`),
+	}
+
+	// Email finds email addresses.
+	Email = &Detector{
+		Name:    "EMAIL",
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	}
+)
+
+// DefaultDetectors are used by Redactors without detectors.
+var DefaultDetectors = []*Detector{CardNumber, PhoneNumber, Email}
+
+// Range is a time range of audio where redacted information was spoken.
+type Range struct {
+	Start, End time.Duration
+
+	// Detector is the name of the detector that found the information.
+	Detector string
+}
+
+// Redactor redacts transcripts.
+type Redactor struct {
+	// Detectors to run, in order of priority: text found by a detector is
+	// not checked by the ones after it.  If nil, DefaultDetectors are used.
+	Detectors []*Detector
+
+	// Token returns the text replacing information found by the detector
+	// of the given name.  If nil, the name in square brackets is used, as
+	// in "[EMAIL]".
+	Token func(name string) string
+}
+
+func (r *Redactor) detectors() []*Detector {
+	if r.Detectors == nil {
+		return DefaultDetectors
+	}
+	return r.Detectors
+}
+
+func (r *Redactor) token(name string) string {
+	if r.Token == nil {
+		return "[" + name + "]"
+	}
+	return r.Token(name)
+}
+
+// match is text found by a detector.
+type match struct {
+	start, end int
+	detector   string
+}
+
+// find returns the matches of the detectors in the text, in order.
+func (r *Redactor) find(text string) []match {
+	var matches []match
+	for _, d := range r.detectors() {
+	next:
+		for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+			if d.Validate != nil && !d.Validate(text[loc[0]:loc[1]]) {
+				continue
+			}
+			for _, m := range matches {
+				if loc[0] < m.end && m.start < loc[1] {
+					continue next
+				}
+			}
+			matches = append(matches, match{loc[0], loc[1], d.Name})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches
+}
+
+// Text returns the text with the information found replaced by tokens.
+func (r *Redactor) Text(text string) string {
+	return r.replace(text, r.find(text))
+}
+
+// replace returns the text with the matches replaced by tokens.
+func (r *Redactor) replace(text string, matches []match) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.start])
+		b.WriteString(r.token(m.detector))
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Segment returns a copy of the segment with the information found in its
+// transcript and words replaced by tokens, and the time ranges of the words
+// replaced, in order.  Runs of words replaced by the same token become a
+// single word spanning their time.
+//
+// Each match in the transcript must be covered by a match in the words of the
+// same detector and holding the same text.  When one is not, as when the
+// segment has no words, or when the information is spelled out in words, as
+// in "john dot doe at example dot com", the whole segment is returned as the
+// time range, and its words are replaced by a single token spanning its time,
+// so that no information is left in the audio or the words.
+func (r *Redactor) Segment(s *juzupb.Segment) (*juzupb.Segment, []Range) {
+	out := proto.Clone(s).(*juzupb.Segment)
+	transcriptMatches := r.find(s.Transcript)
+	out.Transcript = r.replace(s.Transcript, transcriptMatches)
+
+	// Join the words, keeping the span of each in the text.
+	var text strings.Builder
+	spans := make([][2]int, len(s.Words))
+	prevDigits := false
+	for i, w := range s.Words {
+		word, digits := normalizeWord(w.Word)
+		if i > 0 && !(digits && prevDigits) {
+			text.WriteByte(' ')
+		}
+		spans[i][0] = text.Len()
+		text.WriteString(word)
+		spans[i][1] = text.Len()
+		prevDigits = digits
+	}
+
+	// the match of each word, if any
+	matched := make([]int, len(s.Words))
+	matches := r.find(text.String())
+	for i, sp := range spans {
+		matched[i] = -1
+		for k, m := range matches {
+			if sp[0] < m.end && m.start < sp[1] {
+				matched[i] = k
+				break
+			}
+		}
+	}
+
+	var ranges []Range
+	out.Words = out.Words[:0]
+	for i := 0; i < len(s.Words); i++ {
+		k := matched[i]
+		if k < 0 {
+			out.Words = append(out.Words, proto.Clone(s.Words[i]).(*juzupb.WordInfo))
+			continue
+		}
+
+		j := i
+		for j+1 < len(s.Words) && matched[j+1] == k {
+			j++
+		}
+		start := s.Words[i].StartTime.AsDuration()
+		end := start
+		conf := s.Words[i].Confidence
+		for _, w := range s.Words[i : j+1] {
+			if e := w.StartTime.AsDuration() + w.Duration.AsDuration(); e > end {
+				end = e
+			}
+			if w.Confidence < conf {
+				conf = w.Confidence
+			}
+		}
+		out.Words = append(out.Words, &juzupb.WordInfo{
+			Word:       r.token(matches[k].detector),
+			Confidence: conf,
+			StartTime:  durationpb.New(start),
+			Duration:   durationpb.New(end - start),
+		})
+		ranges = append(ranges, Range{start, end, matches[k].detector})
+		i = j
+	}
+
+	if m, ok := uncovered(s.Transcript, transcriptMatches, text.String(), matches); ok {
+		start, end := s.StartTime.AsDuration(), s.EndTime.AsDuration()
+		ranges = []Range{{start, end, m.detector}}
+		if len(s.Words) > 0 {
+			conf := s.Words[0].Confidence
+			for _, w := range s.Words {
+				if w.Confidence < conf {
+					conf = w.Confidence
+				}
+			}
+			out.Words = []*juzupb.WordInfo{{
+				Word:       r.token(m.detector),
+				Confidence: conf,
+				StartTime:  durationpb.New(start),
+				Duration:   durationpb.New(end - start),
+			}}
+		}
+	}
+	return out, ranges
+}
+
+// uncovered returns the first match in the transcript that is not covered by
+// a match in the text of the words.  Each word match covers at most one
+// transcript match, of the same detector, whose text it holds once both are
+// reduced to their letters and digits.
+func uncovered(transcript string, tms []match, words string, wms []match) (match, bool) {
+	used := make([]bool, len(wms))
+next:
+	for _, tm := range tms {
+		t := matchKey(transcript[tm.start:tm.end])
+		for k, wm := range wms {
+			if !used[k] && wm.detector == tm.detector && strings.Contains(matchKey(words[wm.start:wm.end]), t) {
+				used[k] = true
+				continue next
+			}
+		}
+		return tm, true
+	}
+	return match{}, false
+}
+
+// matchKey returns the letters and digits of the text, in lower case, with
+// spelled out digits turned into digits.
+func matchKey(text string) string {
+	var b strings.Builder
+	for _, f := range strings.Fields(text) {
+		f, _ = normalizeWord(f)
+		for _, c := range f {
+			if unicode.IsLetter(c) || unicode.IsDigit(c) {
+				b.WriteRune(unicode.ToLower(c))
+			}
+		}
+	}
+	return b.String()
+}
+
+// Result returns a copy of the result with all its segments redacted, and
+// the time ranges of the words replaced, in order.
+func (r *Redactor) Result(res *juzupb.DiarizationResult) (*juzupb.DiarizationResult, []Range) {
+	out := proto.Clone(res).(*juzupb.DiarizationResult)
+	var ranges []Range
+	for i, s := range res.Segments {
+		var rs []Range
+		out.Segments[i], rs = r.Segment(s)
+		ranges = append(ranges, rs...)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	return out, ranges
+}
+
+var digitWords = map[string]string{
+	"zero": "0", "oh": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+// normalizeWord returns the word with spelled out digits turned into digits,
+// and whether it is made of digits only, leaving aside punctuation.
+func normalizeWord(w string) (string, bool) {
+	t := strings.TrimFunc(w, unicode.IsPunct)
+	if d, ok := digitWords[strings.ToLower(t)]; ok {
+		return d, true
+	}
+	if t == "" {
+		return w, false
+	}
+	for _, c := range t {
+		if c < '0' || c > '9' {
+			return w, false
+		}
+	}
+	return t, true
+}
+
+// luhn tells whether the digits of s pass the Luhn checksum of card numbers.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
@@ -0,0 +1,159 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redact_test
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/redact"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// segment returns a segment with the given words of 500ms each, from the
+// given start in seconds.
+func segment(transcript string, start float64, words ...string) *juzupb.Segment {
+	t := time.Duration(start * float64(time.Second))
+	s := &juzupb.Segment{SpeakerLabel: "1", StartTime: durationpb.New(t), Transcript: transcript}
+	for _, w := range words {
+		s.Words = append(s.Words, &juzupb.WordInfo{
+			Word:       w,
+			Confidence: 0.9,
+			StartTime:  durationpb.New(t),
+			Duration:   durationpb.New(500 * time.Millisecond),
+		})
+		t += 500 * time.Millisecond
+	}
+	s.EndTime = durationpb.New(t)
+	return s
+}
+
+func TestText(t *testing.T) {
+	var r redact.Redactor
+	for _, tc := range []struct{ in, want string }{
+		{"My card is 4111 1111 1111 1111, thanks.", "My card is [CARD_NUMBER], thanks."},
+		{"Not a card: 4111 1111 1111 1112.", "Not a card: 4111 1111 1111 1112."},
+		{"Call me at (555) 123-4567 or +1 555.123.4567", "Call me at [PHONE_NUMBER] or [PHONE_NUMBER]"},
+		{"Write to jane.doe@example.com please", "Write to [EMAIL] please"},
+		{"Order 12345 is late", "Order 12345 is late"},
+	} {
+		if got := r.Text(tc.in); got != tc.want {
+			t.Errorf("Text(%q) = %q; want %q", tc.in, got, tc.want)
+		}
+	}
+
+	r = redact.Redactor{
+		Detectors: []*redact.Detector{{Name: "ORDER", Pattern: regexp.MustCompile(`\border \d+`)}},
+		Token:     func(name string) string { return "***" },
+	}
+	if got := r.Text("My order 12345 at jane@example.com"); got != "My *** at jane@example.com" {
+		t.Errorf("custom detector: got %q", got)
+	}
+}
+
+func words(s *juzupb.Segment) string {
+	var w []string
+	for _, wi := range s.Words {
+		w = append(w, wi.Word)
+	}
+	return strings.Join(w, " ")
+}
+
+func TestSegment(t *testing.T) {
+	var r redact.Redactor
+
+	// digits spelled out in words
+	s := segment("my number is 555 123 4567", 10,
+		"my", "number", "is", "five", "five", "five", "one", "two", "three", "four", "five", "six", "seven")
+	got, ranges := r.Segment(s)
+	if got.Transcript != "my number is [PHONE_NUMBER]" || words(got) != "my number is [PHONE_NUMBER]" {
+		t.Errorf("got transcript %q and words %q", got.Transcript, words(got))
+	}
+	want := []redact.Range{{11500 * time.Millisecond, 16500 * time.Millisecond, "PHONE_NUMBER"}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ranges are %v; want %v", ranges, want)
+	}
+	if w := got.Words[3]; w.StartTime.AsDuration() != 11500*time.Millisecond || w.Duration.AsDuration() != 5*time.Second {
+		t.Errorf("token word is %v", w)
+	}
+	if len(s.Words) != 13 || s.Transcript != "my number is 555 123 4567" {
+		t.Errorf("Segment modified its input")
+	}
+
+	// card number in groups, and email
+	s = segment("", 0, "4111", "1111", "1111", "1111.", "mail", "jane@example.com")
+	got, ranges = r.Segment(s)
+	if words(got) != "[CARD_NUMBER] mail [EMAIL]" {
+		t.Errorf("got words %q", words(got))
+	}
+	want = []redact.Range{{0, 2 * time.Second, "CARD_NUMBER"}, {2500 * time.Millisecond, 3 * time.Second, "EMAIL"}}
+	if !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ranges are %v; want %v", ranges, want)
+	}
+
+	// no words: the whole segment is redacted in the audio
+	s = segment("mail jane@example.com", 1)
+	s.EndTime = durationpb.New(4 * time.Second)
+	got, ranges = r.Segment(s)
+	want = []redact.Range{{time.Second, 4 * time.Second, "EMAIL"}}
+	if got.Transcript != "mail [EMAIL]" || !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got transcript %q and ranges %v", got.Transcript, ranges)
+	}
+
+	// as many matches in the words as in the transcript, but not of the
+	// same information: the whole segment is redacted in the audio
+	for _, s := range []*juzupb.Segment{
+		segment("mail jane@example.com", 1, "call", "555", "123", "4567"),
+		segment("mail jane@example.com", 1, "mail", "john@example.com"),
+	} {
+		_, ranges = r.Segment(s)
+		want = []redact.Range{{time.Second, s.EndTime.AsDuration(), "EMAIL"}}
+		if !reflect.DeepEqual(ranges, want) {
+			t.Errorf("words %q: ranges are %v; want %v", words(s), ranges, want)
+		}
+	}
+
+	// information spelled out in words is not found in them: the words are
+	// replaced by a token spanning the segment
+	s = segment("mail me at john.doe@example.com please", 2,
+		"mail", "me", "at", "john", "dot", "doe", "at", "example", "dot", "com", "please")
+	got, ranges = r.Segment(s)
+	want = []redact.Range{{2 * time.Second, 7500 * time.Millisecond, "EMAIL"}}
+	if got.Transcript != "mail me at [EMAIL] please" || words(got) != "[EMAIL]" || !reflect.DeepEqual(ranges, want) {
+		t.Errorf("got transcript %q, words %q and ranges %v", got.Transcript, words(got), ranges)
+	}
+	if w := got.Words[0]; w.StartTime.AsDuration() != 2*time.Second || w.Duration.AsDuration() != 5500*time.Millisecond {
+		t.Errorf("token word is %v", w)
+	}
+}
+
+func TestResult(t *testing.T) {
+	res := &juzupb.DiarizationResult{Segments: []*juzupb.Segment{
+		segment("at jane@example.com", 5, "at", "jane@example.com"),
+		segment("hello", 0, "hello"),
+	}}
+	var r redact.Redactor
+	got, ranges := r.Result(res)
+	if got.Segments[0].Transcript != "at [EMAIL]" || got.Segments[1].Transcript != "hello" {
+		t.Errorf("got segments %v", got.Segments)
+	}
+	if want := []redact.Range{{5500 * time.Millisecond, 6 * time.Second, "EMAIL"}}; !reflect.DeepEqual(ranges, want) {
+		t.Errorf("ranges are %v; want %v", ranges, want)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rttm writes and reads diarization segments in the Rich Transcription
+// Time Marked (RTTM) format used by diarization evaluation tools.
+//
+// Each segment is a SPEAKER line:
+//
+//	SPEAKER <file> <channel> <onset> <duration> <NA> <NA> <speaker> <NA> <NA>
+//
+// with the onset and duration in seconds.  Only speaker labels and times are
+// kept; transcripts and words are not part of SPEAKER lines.
+package rttm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const na = "<NA>"
+
+// File holds the segments of one channel of one file.
+type File struct {
+	ID       string
+	Channel  int
+	Segments []*juzupb.Segment
+}
+
+// Write writes the segments as SPEAKER lines of the given file ID and channel.
+// Times are written to the millisecond.  Whitespace in the file ID and speaker
+// labels, which would break the fields, is replaced by underscores, and empty
+// labels are written as <NA>.
+func Write(w io.Writer, fileID string, channel int, segments []*juzupb.Segment) error {
+	fileID = field(fileID)
+	for _, s := range segments {
+		start := s.StartTime.AsDuration()
+		dur := s.EndTime.AsDuration() - start
+		if _, err := fmt.Fprintf(w, "SPEAKER %s %d %.3f %.3f %s %s %s %s %s\n",
+			fileID, channel, start.Seconds(), dur.Seconds(), na, na, field(s.SpeakerLabel), na, na); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// field returns s made safe to write as a field.
+func field(s string) string {
+	if s == "" {
+		return na
+	}
+	return strings.Join(strings.Fields(s), "_")
+}
+
+// Read parses the SPEAKER lines of RTTM data, and returns their segments by
+// file and channel, in order of first appearance.  Other types of lines and
+// comments, starting with ";;", are skipped.
+func Read(r io.Reader) ([]*File, error) {
+	type key struct {
+		id      string
+		channel int
+	}
+	var files []*File
+	index := map[key]*File{}
+
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || fields[0] != "SPEAKER" {
+			continue
+		}
+		if len(fields) < 8 {
+			return nil, fmt.Errorf("line %d: SPEAKER line has %d fields; want at least 8", line, len(fields))
+		}
+
+		channel, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid channel %q", line, fields[2])
+		}
+		start, err := parseSeconds(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid onset %q", line, fields[3])
+		}
+		dur, err := parseSeconds(fields[4])
+		if err != nil || dur < 0 {
+			return nil, fmt.Errorf("line %d: invalid duration %q", line, fields[4])
+		}
+		label := fields[7]
+		if label == na {
+			label = ""
+		}
+
+		k := key{fields[1], channel}
+		f := index[k]
+		if f == nil {
+			f = &File{ID: k.id, Channel: k.channel}
+			index[k] = f
+			files = append(files, f)
+		}
+		f.Segments = append(f.Segments, &juzupb.Segment{
+			SpeakerLabel: label,
+			StartTime:    durationpb.New(start),
+			EndTime:      durationpb.New(start + dur),
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// parseSeconds parses a time in seconds, rounded to the nanosecond.
+func parseSeconds(s string) (time.Duration, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(math.Round(f * float64(time.Second))), nil
+}
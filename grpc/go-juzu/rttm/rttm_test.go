@@ -0,0 +1,105 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rttm_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/rttm"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func segment(label string, start, end time.Duration) *juzupb.Segment {
+	return &juzupb.Segment{SpeakerLabel: label, StartTime: durationpb.New(start), EndTime: durationpb.New(end)}
+}
+
+func TestRoundTrip(t *testing.T) {
+	segs := []*juzupb.Segment{
+		segment("0", 0, 1500*time.Millisecond),
+		segment("1", 1500*time.Millisecond, 62*time.Second+5*time.Millisecond),
+		segment("0", 3*time.Hour, 3*time.Hour+time.Millisecond),
+	}
+
+	var buf bytes.Buffer
+	if err := rttm.Write(&buf, "call 1", 1, segs); err != nil {
+		t.Fatalf("could not write RTTM: %v", err)
+	}
+
+	want := "SPEAKER call_1 1 0.000 1.500 <NA> <NA> 0 <NA> <NA>\n" +
+		"SPEAKER call_1 1 1.500 60.505 <NA> <NA> 1 <NA> <NA>\n" +
+		"SPEAKER call_1 1 10800.000 0.001 <NA> <NA> 0 <NA> <NA>\n"
+	if buf.String() != want {
+		t.Errorf("wrote RTTM\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	files, err := rttm.Read(&buf)
+	if err != nil {
+		t.Fatalf("could not read RTTM: %v", err)
+	}
+	if len(files) != 1 || files[0].ID != "call_1" || files[0].Channel != 1 {
+		t.Fatalf("read RTTM files %+v; want call_1 channel 1", files)
+	}
+	if len(files[0].Segments) != len(segs) {
+		t.Fatalf("read %d segments; want %d", len(files[0].Segments), len(segs))
+	}
+	for i, s := range files[0].Segments {
+		if !proto.Equal(s, segs[i]) {
+			t.Errorf("segment %d read as %v; want %v", i, s, segs[i])
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	data := `;; comment
+SPEAKER a 1 0.5 1.25 <NA> <NA> spk1 <NA> <NA>
+SPKR-INFO a 1 <NA> <NA> <NA> unknown spk1 <NA> <NA>
+SPEAKER b 1 0 2 <NA> <NA> <NA> <NA> <NA>
+
+SPEAKER a 2 1 1 <NA> <NA> spk2 <NA>
+SPEAKER a 1 3 1 <NA> <NA> spk2 <NA> <NA>
+`
+	files, err := rttm.Read(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("could not read RTTM: %v", err)
+	}
+
+	var got []string
+	for _, f := range files {
+		for _, s := range f.Segments {
+			got = append(got, f.ID+"/"+string(rune('0'+f.Channel))+"/"+s.SpeakerLabel+"/"+
+				s.StartTime.AsDuration().String()+"-"+s.EndTime.AsDuration().String())
+		}
+	}
+	want := "[a/1/spk1/500ms-1.75s a/1/spk2/3s-4s b/1//0s-2s a/2/spk2/1s-2s]"
+	if s := "[" + strings.Join(got, " ") + "]"; s != want {
+		t.Errorf("read segments %s; want %s", s, want)
+	}
+
+	for _, bad := range []string{
+		"SPEAKER a 1 0.5 1.25 <NA> <NA>",
+		"SPEAKER a x 0.5 1.25 <NA> <NA> spk1 <NA> <NA>",
+		"SPEAKER a 1 start 1.25 <NA> <NA> spk1 <NA> <NA>",
+		"SPEAKER a 1 0.5 -1 <NA> <NA> spk1 <NA> <NA>",
+	} {
+		if _, err := rttm.Read(strings.NewReader(bad)); err == nil {
+			t.Errorf("reading %q: want error, got nil", bad)
+		}
+	}
+}
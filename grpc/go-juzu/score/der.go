@@ -0,0 +1,287 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package score evaluates diarization results against reference annotations,
+// such as those read from RTTM with package rttm: speaker times with the
+// diarization error rate, and transcripts with the word error rate.
+package score
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/assign"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Options configures diarization scoring.
+type Options struct {
+	// Collar excluded from scoring on each side of the boundaries of
+	// reference speech, to allow for imprecise reference annotations.
+	// Evaluations commonly use 250ms, or none.
+	Collar time.Duration
+
+	// SkipOverlap excludes the regions where several reference speakers
+	// speak at once from scoring.
+	SkipOverlap bool
+}
+
+// DiarizationScore is the result of scoring a diarization hypothesis against a
+// reference.
+type DiarizationScore struct {
+	// Scored is the total reference speech time scored, counting each
+	// speaker separately where speakers overlap.
+	Scored time.Duration
+
+	// Errors making up the diarization error rate: reference speech with no
+	// hypothesis speaker, hypothesis speech with no reference speaker, and
+	// speech attributed to the wrong speaker.
+	Missed     time.Duration
+	FalseAlarm time.Duration
+	Confusion  time.Duration
+
+	// Mapping holds the hypothesis speaker mapped to each reference
+	// speaker by the optimal one-to-one mapping used for the DER.
+	// Reference speakers that are not mapped are absent.
+	Mapping map[string]string
+
+	// JER is the Jaccard error rate: the mean over reference speakers of
+	// one minus the Jaccard index of their speech and that of their
+	// hypothesis speaker, under the optimal mapping for the JER.
+	// SpeakerJER holds the error of each reference speaker.
+	JER        float64
+	SpeakerJER map[string]float64
+}
+
+// DER returns the diarization error rate: the total error time divided by the
+// scored time.
+func (s *DiarizationScore) DER() float64 {
+	if s.Scored == 0 {
+		return 0
+	}
+	return float64(s.Missed+s.FalseAlarm+s.Confusion) / float64(s.Scored)
+}
+
+type interval struct {
+	start, end time.Duration
+}
+
+// speakerIntervals returns the speakers of the segments, sorted, and the
+// union of the intervals of each of them.
+func speakerIntervals(segments []*juzupb.Segment) ([]string, [][]interval) {
+	bySpeaker := map[string][]interval{}
+	for _, s := range segments {
+		iv := interval{s.StartTime.AsDuration(), s.EndTime.AsDuration()}
+		if iv.end > iv.start {
+			bySpeaker[s.SpeakerLabel] = append(bySpeaker[s.SpeakerLabel], iv)
+		}
+	}
+
+	var speakers []string
+	for spk := range bySpeaker {
+		speakers = append(speakers, spk)
+	}
+	sort.Strings(speakers)
+
+	intervals := make([][]interval, len(speakers))
+	for i, spk := range speakers {
+		intervals[i] = union(bySpeaker[spk])
+	}
+	return speakers, intervals
+}
+
+// union returns the union of the intervals, sorted.
+func union(ivs []interval) []interval {
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].start < ivs[j].start })
+	var u []interval
+	for _, iv := range ivs {
+		if n := len(u); n > 0 && iv.start <= u[n-1].end {
+			if iv.end > u[n-1].end {
+				u[n-1].end = iv.end
+			}
+			continue
+		}
+		u = append(u, iv)
+	}
+	return u
+}
+
+// tracker tells which intervals of each speaker are active at increasing
+// times.
+type tracker struct {
+	intervals [][]interval
+	next      []int
+}
+
+func newTracker(intervals [][]interval) *tracker {
+	return &tracker{intervals: intervals, next: make([]int, len(intervals))}
+}
+
+// active returns the speakers whose intervals hold t.  Calls must be made
+// with non-decreasing times.
+func (tr *tracker) active(t time.Duration) []int {
+	var spks []int
+	for i, ivs := range tr.intervals {
+		for tr.next[i] < len(ivs) && ivs[tr.next[i]].end <= t {
+			tr.next[i]++
+		}
+		if tr.next[i] < len(ivs) && ivs[tr.next[i]].start <= t {
+			spks = append(spks, i)
+		}
+	}
+	return spks
+}
+
+// region is a stretch of time over which the same speakers are active.
+type region struct {
+	dur      time.Duration
+	ref, hyp []int
+}
+
+// regions splits time into the scored regions over which the active
+// reference and hypothesis speakers do not change.
+func regions(ref, hyp [][]interval, opts Options) []region {
+	var times []time.Duration
+	var noScore []interval
+	for _, ivs := range ref {
+		for _, iv := range ivs {
+			times = append(times, iv.start, iv.end)
+			if opts.Collar > 0 {
+				noScore = append(noScore,
+					interval{iv.start - opts.Collar, iv.start + opts.Collar},
+					interval{iv.end - opts.Collar, iv.end + opts.Collar})
+			}
+		}
+	}
+	for _, ivs := range hyp {
+		for _, iv := range ivs {
+			times = append(times, iv.start, iv.end)
+		}
+	}
+	noScore = union(noScore)
+	for _, iv := range noScore {
+		times = append(times, iv.start, iv.end)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	refs, hyps := newTracker(ref), newTracker(hyp)
+	skips := newTracker([][]interval{noScore})
+
+	var rs []region
+	for i := 0; i+1 < len(times); i++ {
+		t, dur := times[i], times[i+1]-times[i]
+		if dur == 0 {
+			continue
+		}
+		r := region{dur: dur, ref: refs.active(t), hyp: hyps.active(t)}
+		if len(skips.active(t)) > 0 || opts.SkipOverlap && len(r.ref) > 1 {
+			continue
+		}
+		if len(r.ref) > 0 || len(r.hyp) > 0 {
+			rs = append(rs, r)
+		}
+	}
+	return rs
+}
+
+// Diarization scores the hypothesis segments against the reference segments,
+// which both cover the same audio.
+//
+// The speakers of the hypothesis are mapped one-to-one to those of the
+// reference so as to maximize the time they overlap, and the diarization
+// error is then broken down into missed speech, false alarms and speaker
+// confusion, as done by NIST's md-eval.  Collars and skipped overlaps are
+// excluded from the JER as well.
+func Diarization(ref, hyp []*juzupb.Segment, opts Options) *DiarizationScore {
+	refSpks, refIvs := speakerIntervals(ref)
+	hypSpks, hypIvs := speakerIntervals(hyp)
+	rs := regions(refIvs, hypIvs, opts)
+
+	// time of each speaker, and overlap of each pair of speakers
+	refTime := make([]time.Duration, len(refSpks))
+	hypTime := make([]time.Duration, len(hypSpks))
+	overlap := make([][]float64, len(refSpks))
+	for i := range overlap {
+		overlap[i] = make([]float64, len(hypSpks))
+	}
+	for _, r := range rs {
+		for _, i := range r.ref {
+			refTime[i] += r.dur
+			for _, j := range r.hyp {
+				overlap[i][j] += float64(r.dur)
+			}
+		}
+		for _, j := range r.hyp {
+			hypTime[j] += r.dur
+		}
+	}
+
+	s := &DiarizationScore{Mapping: map[string]string{}, SpeakerJER: map[string]float64{}}
+
+	mapping := assign.Maximize(overlap)
+	for i, j := range mapping {
+		if j >= 0 && overlap[i][j] > 0 {
+			s.Mapping[refSpks[i]] = hypSpks[j]
+		} else {
+			mapping[i] = -1
+		}
+	}
+
+	for _, r := range rs {
+		nref, nhyp := len(r.ref), len(r.hyp)
+		s.Scored += time.Duration(nref) * r.dur
+		if nref > nhyp {
+			s.Missed += time.Duration(nref-nhyp) * r.dur
+		} else {
+			s.FalseAlarm += time.Duration(nhyp-nref) * r.dur
+		}
+
+		correct := 0
+		for _, i := range r.ref {
+			for _, j := range r.hyp {
+				if mapping[i] == j {
+					correct++
+				}
+			}
+		}
+		matched := nref
+		if nhyp < matched {
+			matched = nhyp
+		}
+		s.Confusion += time.Duration(matched-correct) * r.dur
+	}
+
+	// The JER uses the mapping that maximizes the total Jaccard index.
+	jaccard := make([][]float64, len(refSpks))
+	for i := range jaccard {
+		jaccard[i] = make([]float64, len(hypSpks))
+		for j := range jaccard[i] {
+			if u := float64(refTime[i]+hypTime[j]) - overlap[i][j]; u > 0 {
+				jaccard[i][j] = overlap[i][j] / u
+			}
+		}
+	}
+	for i, j := range assign.Maximize(jaccard) {
+		jer := 1.0
+		if j >= 0 {
+			jer -= jaccard[i][j]
+		}
+		s.SpeakerJER[refSpks[i]] = jer
+		s.JER += jer
+	}
+	if len(refSpks) > 0 {
+		s.JER /= float64(len(refSpks))
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package score_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/score"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func seg(label string, start, end float64) *juzupb.Segment {
+	return &juzupb.Segment{
+		SpeakerLabel: label,
+		StartTime:    durationpb.New(time.Duration(start * float64(time.Second))),
+		EndTime:      durationpb.New(time.Duration(end * float64(time.Second))),
+	}
+}
+
+func TestDiarization(t *testing.T) {
+	// B speaks from 10s to 20s, and A from 0s to 10s and over B from 15s
+	// to 18s.  The hypothesis changes speaker 1s early, and misses the
+	// overlap.
+	ref := []*juzupb.Segment{seg("A", 0, 10), seg("B", 10, 20), seg("A", 15, 18)}
+	hyp := []*juzupb.Segment{seg("x", 0, 5), seg("x", 4, 9), seg("y", 9, 20)}
+
+	for _, tc := range []struct {
+		name                         string
+		opts                         score.Options
+		scored, missed, fa, confused float64
+		jer                          float64
+	}{
+		{"default", score.Options{}, 23, 3, 0, 1, (4.0/13 + 1.0/11) / 2},
+		{"skip overlap", score.Options{SkipOverlap: true}, 17, 0, 0, 1, (1.0/10 + 1.0/8) / 2},
+		{"collar", score.Options{Collar: 500 * time.Millisecond}, 18, 2, 0, 0.5, (2.5/11 + 1.0/15) / 2},
+	} {
+		s := score.Diarization(ref, hyp, tc.opts)
+
+		for _, c := range []struct {
+			what      string
+			got, want float64
+		}{
+			{"scored", s.Scored.Seconds(), tc.scored},
+			{"missed", s.Missed.Seconds(), tc.missed},
+			{"false alarm", s.FalseAlarm.Seconds(), tc.fa},
+			{"confusion", s.Confusion.Seconds(), tc.confused},
+			{"DER", s.DER(), (tc.missed + tc.fa + tc.confused) / tc.scored},
+			{"JER", s.JER, tc.jer},
+		} {
+			if math.Abs(c.got-c.want) > 1e-9 {
+				t.Errorf("%s: %s is %v; want %v", tc.name, c.what, c.got, c.want)
+			}
+		}
+		if s.Mapping["A"] != "x" || s.Mapping["B"] != "y" {
+			t.Errorf("%s: speaker mapping is %v; want A:x B:y", tc.name, s.Mapping)
+		}
+	}
+
+	// extra hypothesis speech is a false alarm, and an extra speaker is
+	// confusion
+	s := score.Diarization([]*juzupb.Segment{seg("A", 0, 10)},
+		[]*juzupb.Segment{seg("x", 0, 6), seg("y", 6, 12)}, score.Options{})
+	if s.FalseAlarm != 2*time.Second || s.Confusion != 4*time.Second || s.Missed != 0 {
+		t.Errorf("got false alarm %v, confusion %v, missed %v; want 2s, 4s, 0s", s.FalseAlarm, s.Confusion, s.Missed)
+	}
+	if math.Abs(s.SpeakerJER["A"]-0.4) > 1e-9 {
+		t.Errorf("JER of A is %v; want 0.4", s.SpeakerJER["A"])
+	}
+
+	// empty hypothesis
+	s = score.Diarization([]*juzupb.Segment{seg("A", 0, 10)}, nil, score.Options{})
+	if s.DER() != 1 || s.JER != 1 || len(s.Mapping) != 0 {
+		t.Errorf("empty hypothesis: got DER %v, JER %v, mapping %v; want 1, 1, none", s.DER(), s.JER, s.Mapping)
+	}
+}
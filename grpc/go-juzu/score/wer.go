@@ -0,0 +1,229 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package score
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/assign"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// WordScore is the result of aligning hypothesis words with reference words.
+type WordScore struct {
+	// Words is the number of reference words.
+	Words int
+
+	// Errors of the alignment with the fewest errors.
+	Substitutions int
+	Deletions     int
+	Insertions    int
+
+	// Mapping holds the hypothesis speaker mapped to each reference
+	// speaker, for scores computed by CPWER.  Reference speakers that are
+	// not mapped are absent.
+	Mapping map[string]string
+}
+
+// Errors returns the total number of errors.
+func (s *WordScore) Errors() int {
+	return s.Substitutions + s.Deletions + s.Insertions
+}
+
+// WER returns the word error rate: the number of errors divided by the number
+// of reference words.
+func (s *WordScore) WER() float64 {
+	if s.Words == 0 {
+		return 0
+	}
+	return float64(s.Errors()) / float64(s.Words)
+}
+
+func (s *WordScore) add(o *WordScore) {
+	s.Words += o.Words
+	s.Substitutions += o.Substitutions
+	s.Deletions += o.Deletions
+	s.Insertions += o.Insertions
+}
+
+// Normalize returns the word in the form in which words are compared: in
+// lower case, without leading or trailing punctuation.
+func Normalize(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, unicode.IsPunct))
+}
+
+// Tokenize splits text into normalized words, such as to score against a
+// reference transcript held as plain text.
+func Tokenize(text string) []string {
+	var words []string
+	for _, f := range strings.Fields(text) {
+		if w := Normalize(f); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words
+}
+
+// timedWord is a word with its start time.
+type timedWord struct {
+	text  string
+	start time.Duration
+}
+
+// segmentWords returns the normalized words of the segments, from their word
+// timestamps, or from their transcripts with the start time of the segment
+// when they have none.
+func segmentWords(segments []*juzupb.Segment) []timedWord {
+	var words []timedWord
+	for _, s := range segments {
+		if len(s.Words) == 0 {
+			for _, w := range Tokenize(s.Transcript) {
+				words = append(words, timedWord{w, s.StartTime.AsDuration()})
+			}
+			continue
+		}
+		for _, w := range s.Words {
+			if text := Normalize(w.Word); text != "" {
+				words = append(words, timedWord{text, w.StartTime.AsDuration()})
+			}
+		}
+	}
+	sort.SliceStable(words, func(i, j int) bool { return words[i].start < words[j].start })
+	return words
+}
+
+func texts(words []timedWord) []string {
+	t := make([]string, len(words))
+	for i, w := range words {
+		t[i] = w.text
+	}
+	return t
+}
+
+// Words returns the normalized words of the segments of all speakers, in the
+// order of their start times.
+func Words(segments []*juzupb.Segment) []string {
+	return texts(segmentWords(segments))
+}
+
+// SpeakerWords returns the normalized words of each speaker of the segments,
+// in the order of their start times.
+func SpeakerWords(segments []*juzupb.Segment) map[string][]string {
+	bySpeaker := map[string][]*juzupb.Segment{}
+	for _, s := range segments {
+		bySpeaker[s.SpeakerLabel] = append(bySpeaker[s.SpeakerLabel], s)
+	}
+	words := map[string][]string{}
+	for spk, segs := range bySpeaker {
+		words[spk] = Words(segs)
+	}
+	return words
+}
+
+// WER aligns the hypothesis words with the reference words, and counts the
+// errors of the alignment with the fewest errors.  Words are compared as they
+// are; see Tokenize and Words to get normalized words.
+func WER(ref, hyp []string) *WordScore {
+	// Each cell holds the errors of aligning a prefix of ref with a prefix
+	// of hyp.  Only the previous row is kept.
+	prev := make([]WordScore, len(hyp)+1)
+	cur := make([]WordScore, len(hyp)+1)
+	for j := 1; j <= len(hyp); j++ {
+		prev[j] = WordScore{Insertions: j}
+	}
+
+	for i := 1; i <= len(ref); i++ {
+		cur[0] = WordScore{Deletions: i}
+		for j := 1; j <= len(hyp); j++ {
+			best := prev[j-1]
+			if ref[i-1] != hyp[j-1] {
+				best.Substitutions++
+			}
+			if prev[j].Errors()+1 < best.Errors() {
+				best = prev[j]
+				best.Deletions++
+			}
+			if cur[j-1].Errors()+1 < best.Errors() {
+				best = cur[j-1]
+				best.Insertions++
+			}
+			cur[j] = best
+		}
+		prev, cur = cur, prev
+	}
+
+	s := prev[len(hyp)]
+	s.Words = len(ref)
+	return &s
+}
+
+// CPWER computes the concatenated minimum-permutation word error rate of the
+// hypothesis segments against the reference segments, which both hold
+// transcripts of the same audio.
+//
+// The words of each speaker are concatenated in time order, and the speakers
+// of the hypothesis are mapped one-to-one to those of the reference so as to
+// minimize the total number of errors.  The words of speakers left unmapped
+// count as deletions or insertions.  Speaker confusion is therefore scored as
+// word errors, which the plain WER of all words ignores.
+func CPWER(ref, hyp []*juzupb.Segment) *WordScore {
+	refWords, hypWords := SpeakerWords(ref), SpeakerWords(hyp)
+	refSpks, hypSpks := sortedKeys(refWords), sortedKeys(hypWords)
+
+	// Score every pair, weighted by the errors it saves over leaving both
+	// speakers unmapped.
+	pairs := make([][]*WordScore, len(refSpks))
+	saved := make([][]float64, len(refSpks))
+	for i, r := range refSpks {
+		pairs[i] = make([]*WordScore, len(hypSpks))
+		saved[i] = make([]float64, len(hypSpks))
+		for j, h := range hypSpks {
+			pairs[i][j] = WER(refWords[r], hypWords[h])
+			saved[i][j] = float64(len(refWords[r]) + len(hypWords[h]) - pairs[i][j].Errors())
+		}
+	}
+
+	s := &WordScore{Mapping: map[string]string{}}
+	mappedHyp := make([]bool, len(hypSpks))
+	for i, j := range assign.Maximize(saved) {
+		r := refSpks[i]
+		if j >= 0 && saved[i][j] > 0 {
+			s.Mapping[r] = hypSpks[j]
+			s.add(pairs[i][j])
+			mappedHyp[j] = true
+			continue
+		}
+		s.Words += len(refWords[r])
+		s.Deletions += len(refWords[r])
+	}
+	for j, h := range hypSpks {
+		if !mappedHyp[j] {
+			s.Insertions += len(hypWords[h])
+		}
+	}
+	return s
+}
+
+func sortedKeys(m map[string][]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package score_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/score"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestWER(t *testing.T) {
+	for _, tc := range []struct {
+		ref, hyp      string
+		sub, del, ins int
+		wer           float64
+	}{
+		{"the cat sat on the mat", "the cat sat on the mat", 0, 0, 0, 0},
+		{"the cat sat on the mat", "the bat sat on mat", 1, 1, 0, 2.0 / 6},
+		{"the cat sat", "well the cat sat down", 0, 0, 2, 2.0 / 3},
+		{"The cat, sat.", "the CAT sat", 0, 0, 0, 0},
+		{"", "hello", 0, 0, 1, 0},
+		{"hello", "", 0, 1, 0, 1},
+	} {
+		s := score.WER(score.Tokenize(tc.ref), score.Tokenize(tc.hyp))
+		if s.Substitutions != tc.sub || s.Deletions != tc.del || s.Insertions != tc.ins || s.WER() != tc.wer {
+			t.Errorf("WER(%q, %q) = %+v (%v); want %d substitutions, %d deletions, %d insertions (%v)",
+				tc.ref, tc.hyp, *s, s.WER(), tc.sub, tc.del, tc.ins, tc.wer)
+		}
+	}
+}
+
+func wordSeg(label string, start float64, transcript string, words ...string) *juzupb.Segment {
+	s := seg(label, start, start+float64(len(words)+1))
+	s.Transcript = transcript
+	for i, w := range words {
+		s.Words = append(s.Words, &juzupb.WordInfo{
+			Word:      w,
+			StartTime: durationpb.New(time.Duration((start + float64(i)) * float64(time.Second))),
+			Duration:  durationpb.New(time.Second),
+		})
+	}
+	return s
+}
+
+func TestWords(t *testing.T) {
+	// words of overlapping segments are interleaved by start time, and
+	// segments without word timestamps use their transcripts
+	segs := []*juzupb.Segment{
+		wordSeg("A", 0, "", "one", "three", "five"),
+		wordSeg("B", 1.5, "", "four"),
+		wordSeg("B", 0.5, "Two!"),
+	}
+	if got, want := score.Words(segs), []string{"one", "two", "three", "four", "five"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Words = %q; want %q", got, want)
+	}
+}
+
+func TestCPWER(t *testing.T) {
+	ref := []*juzupb.Segment{
+		wordSeg("A", 0, "hello there how are you"),
+		wordSeg("B", 5, "fine thanks"),
+		wordSeg("C", 8, "hi"),
+	}
+	// Speaker 2 says one of A's words, and speaker 3 is misrecognized.
+	hyp := []*juzupb.Segment{
+		wordSeg("1", 0, "", "hello", "there", "how", "are"),
+		wordSeg("2", 4, "", "you", "fine", "thanks"),
+		wordSeg("3", 9, "", "um"),
+	}
+
+	s := score.CPWER(ref, hyp)
+	want := &score.WordScore{
+		Words:         8,
+		Substitutions: 1,
+		Deletions:     1,
+		Insertions:    1,
+		Mapping:       map[string]string{"A": "1", "B": "2", "C": "3"},
+	}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("CPWER = %+v; want %+v", *s, *want)
+	}
+
+	// the plain WER does not see the speaker error
+	if w := score.WER(score.Words(ref), score.Words(hyp)); w.Errors() != 1 {
+		t.Errorf("WER has %d errors; want 1", w.Errors())
+	}
+}
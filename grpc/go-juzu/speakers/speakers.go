@@ -0,0 +1,276 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package speakers renames the speakers of diarization results, whose labels
+// are opaque strings such as "0" and "1", and proposes names for them from
+// their roles in the conversation.
+//
+// For instance, in calls to a contact center where the agent speaks first:
+//
+//	m := speakers.Classify(result,
+//		speakers.Keywords("Agent", "thank you for calling"),
+//		speakers.FirstSpeaker("Agent"),
+//		speakers.Others("Customer"))
+//	result = speakers.Apply(result, m)
+package speakers
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/overlap"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/golang/protobuf/proto"
+)
+
+// Mapping maps speaker labels to new labels.
+type Mapping map[string]string
+
+// label returns the new label of l, which is l itself if it is not mapped.
+func (m Mapping) label(l string) string {
+	if n, ok := m[l]; ok {
+		return n
+	}
+	return l
+}
+
+// labels returns the new labels, without duplicates.
+func (m Mapping) labels(ls []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, l := range ls {
+		n := m.label(l)
+		if !seen[n] {
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Apply returns a copy of the result with its speakers renamed in its
+// segments, speaker labels and overlapping speech regions.  Labels that the
+// mapping does not hold are kept.  Speakers mapped to the same label become
+// a single speaker, so overlap regions left with a single speaker are
+// dropped, and touching regions left with the same speakers are joined.
+func Apply(r *juzupb.DiarizationResult, m Mapping) *juzupb.DiarizationResult {
+	out := proto.Clone(r).(*juzupb.DiarizationResult)
+	for _, s := range out.Segments {
+		s.SpeakerLabel = m.label(s.SpeakerLabel)
+	}
+	out.SpeakerLabels = m.labels(out.SpeakerLabels)
+	for _, o := range out.Overlaps {
+		o.SpeakerLabels = m.labels(o.SpeakerLabels)
+	}
+	out.Overlaps = overlap.Clean(out.Overlaps)
+	return out
+}
+
+// A Rule proposes roles for some of the speakers of a result.
+type Rule func(r *juzupb.DiarizationResult) Mapping
+
+// Classify proposes a mapping of the speakers of the result to roles, by
+// applying the rules in order.  A rule may only give a role to speakers that
+// earlier rules did not classify, and only if earlier rules did not give the
+// role, so that each rule acts as a fallback for the ones before it.
+func Classify(r *juzupb.DiarizationResult, rules ...Rule) Mapping {
+	m := make(Mapping)
+	given := make(map[string]bool)
+	for _, rule := range rules {
+		proposed := rule(r)
+		for label, role := range proposed {
+			if _, ok := m[label]; !ok && !given[role] {
+				m[label] = role
+			}
+		}
+		for _, role := range proposed {
+			given[role] = true
+		}
+	}
+	return m
+}
+
+// labels returns the speaker labels of the result: those listed and those of
+// its segments, in that order.
+func labels(r *juzupb.DiarizationResult) []string {
+	seen := make(map[string]bool)
+	var ls []string
+	add := func(l string) {
+		if !seen[l] {
+			seen[l] = true
+			ls = append(ls, l)
+		}
+	}
+	for _, l := range r.SpeakerLabels {
+		add(l)
+	}
+	for _, s := range r.Segments {
+		add(s.SpeakerLabel)
+	}
+	return ls
+}
+
+// FirstSpeaker gives the role to the speaker of the segment that starts
+// first.
+func FirstSpeaker(role string) Rule {
+	return func(r *juzupb.DiarizationResult) Mapping {
+		var first *juzupb.Segment
+		for _, s := range r.Segments {
+			if first == nil || s.StartTime.AsDuration() < first.StartTime.AsDuration() {
+				first = s
+			}
+		}
+		if first == nil {
+			return nil
+		}
+		return Mapping{first.SpeakerLabel: role}
+	}
+}
+
+// MostTalkative gives the role to the speaker with the longest total
+// duration of segments.
+func MostTalkative(role string) Rule {
+	return func(r *juzupb.DiarizationResult) Mapping {
+		talk := make(map[string]time.Duration)
+		for _, s := range r.Segments {
+			talk[s.SpeakerLabel] += s.EndTime.AsDuration() - s.StartTime.AsDuration()
+		}
+		// No speaker is the most talkative if none has any segment of
+		// some duration.
+		var best string
+		var most time.Duration
+		for _, l := range labels(r) {
+			if talk[l] > most {
+				best, most = l, talk[l]
+			}
+		}
+		if most == 0 {
+			return nil
+		}
+		return Mapping{best: role}
+	}
+}
+
+// Keywords gives the role to the speaker whose transcripts hold the most
+// occurrences of the keywords, which may be phrases.  Matching ignores case
+// and punctuation.  No speaker gets the role if none says any of the
+// keywords.
+func Keywords(role string, keywords ...string) Rule {
+	var phrases []string
+	for _, k := range keywords {
+		if p := normalize(k); p != "" {
+			phrases = append(phrases, " "+p+" ")
+		}
+	}
+
+	return func(r *juzupb.DiarizationResult) Mapping {
+		count := make(map[string]int)
+		for _, s := range r.Segments {
+			text := " " + normalize(s.Transcript) + " "
+			for _, p := range phrases {
+				count[s.SpeakerLabel] += strings.Count(text, p)
+			}
+		}
+		var best string
+		most := 0
+		for _, l := range labels(r) {
+			if count[l] > most {
+				best, most = l, count[l]
+			}
+		}
+		if most == 0 {
+			return nil
+		}
+		return Mapping{best: role}
+	}
+}
+
+// Channel gives each speaker the role of the channel in which it is loudest,
+// for results of the mix of a recording where each channel holds one side of a
+// conversation, such as a call with the agent on the left and the customer on
+// the right.  The recording is given as interleaved 16-bit little endian PCM
+// in the given format, and roles are given in channel order.  Speakers whose
+// segments are silent, or loudest in a channel without a role, are left alone.
+func Channel(pcm []byte, f audio.Format, roles ...string) Rule {
+	return func(r *juzupb.DiarizationResult) Mapping {
+		if f.SampleRate <= 0 || f.Channels <= 0 {
+			return nil
+		}
+		frames := int64(len(pcm) / f.FrameSize())
+		frame := func(t time.Duration) int64 {
+			i := int64(t) * int64(f.SampleRate) / int64(time.Second)
+			if i < 0 {
+				return 0
+			}
+			if i > frames {
+				return frames
+			}
+			return i
+		}
+
+		// energy of each channel during the segments of each speaker
+		energy := make(map[string][]float64)
+		for _, s := range r.Segments {
+			e := energy[s.SpeakerLabel]
+			if e == nil {
+				e = make([]float64, f.Channels)
+				energy[s.SpeakerLabel] = e
+			}
+			for i := frame(s.StartTime.AsDuration()); i < frame(s.EndTime.AsDuration()); i++ {
+				for c := range e {
+					v := float64(int16(binary.LittleEndian.Uint16(pcm[(int(i)*f.Channels+c)*2:])))
+					e[c] += v * v
+				}
+			}
+		}
+
+		m := make(Mapping)
+		for _, l := range labels(r) {
+			loudest, most := -1, 0.0
+			for c, v := range energy[l] {
+				if v > most {
+					loudest, most = c, v
+				}
+			}
+			if loudest >= 0 && loudest < len(roles) {
+				m[l] = roles[loudest]
+			}
+		}
+		return m
+	}
+}
+
+// Others gives the role to all speakers.  As rules only classify speakers
+// that earlier rules did not, it is meant to come last.
+func Others(role string) Rule {
+	return func(r *juzupb.DiarizationResult) Mapping {
+		m := make(Mapping)
+		for _, l := range labels(r) {
+			m[l] = role
+		}
+		return m
+	}
+}
+
+// normalize returns the words of the text, in lower case and without
+// punctuation, separated by single spaces.
+func normalize(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	return strings.Join(words, " ")
+}
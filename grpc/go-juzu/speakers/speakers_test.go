@@ -0,0 +1,168 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package speakers_test
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/speakers"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func segment(label string, start, end time.Duration, transcript string) *juzupb.Segment {
+	return &juzupb.Segment{
+		SpeakerLabel: label,
+		StartTime:    durationpb.New(start),
+		EndTime:      durationpb.New(end),
+		Transcript:   transcript,
+	}
+}
+
+func call() *juzupb.DiarizationResult {
+	return &juzupb.DiarizationResult{
+		Segments: []*juzupb.Segment{
+			segment("1", 0, 2*time.Second, "Hello?"),
+			segment("0", 2*time.Second, 6*time.Second, "Hi, thank you for calling Acme. How can I help you?"),
+			segment("1", 6*time.Second, 20*time.Second, "My order never arrived, and I'd like a refund."),
+			segment("2", 20*time.Second, 21*time.Second, "Beep."),
+		},
+		SpeakerLabels: []string{"0", "1", "2"},
+		Overlaps: []*juzupb.OverlapRegion{{
+			StartTime:     durationpb.New(time.Second),
+			EndTime:       durationpb.New(2 * time.Second),
+			SpeakerLabels: []string{"0", "1"},
+		}},
+	}
+}
+
+func TestApply(t *testing.T) {
+	r := call()
+	got := speakers.Apply(r, speakers.Mapping{"0": "Agent", "1": "Customer", "2": "Customer"})
+
+	var labels []string
+	for _, s := range got.Segments {
+		labels = append(labels, s.SpeakerLabel)
+	}
+	if want := []string{"Customer", "Agent", "Customer", "Customer"}; !reflect.DeepEqual(labels, want) {
+		t.Errorf("segment labels are %q; want %q", labels, want)
+	}
+	if want := []string{"Agent", "Customer"}; !reflect.DeepEqual(got.SpeakerLabels, want) {
+		t.Errorf("speaker labels are %q; want %q", got.SpeakerLabels, want)
+	}
+	if want := []string{"Agent", "Customer"}; !reflect.DeepEqual(got.Overlaps[0].SpeakerLabels, want) {
+		t.Errorf("overlap labels are %q; want %q", got.Overlaps[0].SpeakerLabels, want)
+	}
+	if r.Segments[0].SpeakerLabel != "1" || r.SpeakerLabels[0] != "0" {
+		t.Errorf("Apply modified its input")
+	}
+
+	// unmapped labels are kept
+	got = speakers.Apply(r, speakers.Mapping{"0": "Alice"})
+	if want := []string{"Alice", "1", "2"}; !reflect.DeepEqual(got.SpeakerLabels, want) {
+		t.Errorf("speaker labels are %q; want %q", got.SpeakerLabels, want)
+	}
+
+	// overlaps of speakers merged into one are dropped
+	got = speakers.Apply(r, speakers.Mapping{"0": "Caller", "1": "Caller"})
+	if len(got.Overlaps) != 0 {
+		t.Errorf("overlaps are %v; want none", got.Overlaps)
+	}
+	if len(r.Overlaps) != 1 || len(r.Overlaps[0].SpeakerLabels) != 2 {
+		t.Errorf("Apply modified the overlaps of its input")
+	}
+
+	// touching overlaps left with the same speakers are joined
+	r.Overlaps = append(r.Overlaps, &juzupb.OverlapRegion{
+		StartTime:     durationpb.New(2 * time.Second),
+		EndTime:       durationpb.New(3 * time.Second),
+		SpeakerLabels: []string{"0", "1", "2"},
+	})
+	got = speakers.Apply(r, speakers.Mapping{"2": "1"})
+	if len(got.Overlaps) != 1 || got.Overlaps[0].EndTime.AsDuration() != 3*time.Second {
+		t.Errorf("overlaps are %v; want one from 1s to 3s", got.Overlaps)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules []speakers.Rule
+		want  speakers.Mapping
+	}{
+		{
+			"first speaker",
+			[]speakers.Rule{speakers.FirstSpeaker("Agent"), speakers.Others("Customer")},
+			speakers.Mapping{"1": "Agent", "0": "Customer", "2": "Customer"},
+		},
+		{
+			"keywords before first speaker",
+			[]speakers.Rule{
+				speakers.Keywords("Agent", "Thank you for calling", "how can I help"),
+				speakers.FirstSpeaker("Agent"),
+				speakers.Others("Customer"),
+			},
+			speakers.Mapping{"0": "Agent", "1": "Customer", "2": "Customer"},
+		},
+		{
+			"no keywords",
+			[]speakers.Rule{speakers.Keywords("Agent", "goodbye"), speakers.MostTalkative("Customer")},
+			speakers.Mapping{"1": "Customer"},
+		},
+	} {
+		if got := speakers.Classify(call(), tc.rules...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v; want %v", tc.name, got, tc.want)
+		}
+	}
+
+	// a stereo call at 100Hz, with speaker 0 on the left and speaker 1 on
+	// the right, and silence at the end
+	f := audio.Format{SampleRate: 100, Channels: 2}
+	pcm := make([]byte, 21*100*f.FrameSize())
+	for i := 0; i < 20*100; i++ {
+		c := 1
+		if i >= 2*100 && i < 6*100 {
+			c = 0
+		}
+		binary.LittleEndian.PutUint16(pcm[(2*i+c)*2:], 1000)
+		binary.LittleEndian.PutUint16(pcm[(2*i+1-c)*2:], 10)
+	}
+	for _, tc := range []struct {
+		name  string
+		roles []string
+		want  speakers.Mapping
+	}{
+		{"channel", []string{"Agent", "Customer"}, speakers.Mapping{"0": "Agent", "1": "Customer"}},
+		{"channel without role", []string{"Agent"}, speakers.Mapping{"0": "Agent"}},
+	} {
+		if got := speakers.Classify(call(), speakers.Channel(pcm, f, tc.roles...)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v; want %v", tc.name, got, tc.want)
+		}
+	}
+	if got := speakers.Classify(call(), speakers.Channel(pcm, audio.Format{}, "Agent")); len(got) != 0 {
+		t.Errorf("channel of audio without format: got %v; want none", got)
+	}
+
+	// speakers without segments neither talk nor say keywords
+	r := &juzupb.DiarizationResult{SpeakerLabels: []string{"0", "1"}}
+	got := speakers.Classify(r, speakers.Keywords("Agent", "hello"), speakers.MostTalkative("Customer"))
+	if len(got) != 0 {
+		t.Errorf("speakers without segments: got %v; want none", got)
+	}
+}
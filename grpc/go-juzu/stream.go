@@ -0,0 +1,331 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/flac"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/vad"
+)
+
+// AudioStream is a streaming diarization call to which the caller pushes
+// audio, as an alternative to StreamingDiarize reading it from an io.Reader.
+//
+// Audio is sent with Write, and the end of the audio must be marked with
+// CloseAudio, after which the server completes the diarization.  Wait then
+// returns once all responses have been handled.
+//
+// Write and CloseAudio must not be called concurrently.
+type AudioStream struct {
+	stream juzupb.Juzu_StreamingDiarizeClient
+	w      *audioWriter
+	done   chan struct{}
+	err    error // error that ended the call, valid once done is closed
+}
+
+// NewAudioStream starts a streaming diarization call set up using the given
+// cfg, and returns the AudioStream to which audio for it is written.
+//
+// Unlike StreamingDiarize, which passes responses to its handler from the
+// calling goroutine, NewAudioStream passes responses received from the server
+// to handlerFunc from a separate goroutine.
+//
+// Cancelling the context aborts the call.  CallOptions may be given to
+// override settings for this call only.
+func (c *Client) NewAudioStream(
+	ctx context.Context,
+	cfg *juzupb.DiarizationConfig,
+	handlerFunc DiarizationResponseHandler,
+	opts ...CallOption,
+) (*AudioStream, error) {
+
+	s, cfg, err := c.openAudioStream(ctx, cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sendConfig(cfg); err != nil {
+		// the actual status of the stream is only available from Recv
+		if _, err = s.stream.Recv(); err == nil || err == io.EOF {
+			err = fmt.Errorf("stream closed by server")
+		}
+		return nil, fmt.Errorf("unable to start streaming diarization: %v", err)
+	}
+
+	go s.receive(handlerFunc)
+
+	return s, nil
+}
+
+// openAudioStream starts a streaming diarization call, without sending
+// anything on it yet, and returns it along with the config to send first.
+func (c *Client) openAudioStream(
+	ctx context.Context,
+	cfg *juzupb.DiarizationConfig,
+	opts []CallOption,
+) (*AudioStream, *juzupb.DiarizationConfig, error) {
+
+	var cc callConfig
+	for _, opt := range opts {
+		if err := opt(&cc); err != nil {
+			return nil, nil, fmt.Errorf("unable to start streaming diarization: %v", err)
+		}
+	}
+
+	cfg, sendOpts, err := c.sendOptions(cfg, &cc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to start streaming diarization: %v", err)
+	}
+
+	stream, err := c.juzu.StreamingDiarize(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to start streaming diarization: %v", err)
+	}
+
+	w, err := newAudioWriter(stream, sendOpts)
+	if err != nil {
+		_ = stream.CloseSend()
+		return nil, nil, fmt.Errorf("unable to start streaming diarization: %v", err)
+	}
+
+	return &AudioStream{stream: stream, w: w, done: make(chan struct{})}, cfg, nil
+}
+
+// sendConfig sends the config message, which needs to be the first message of
+// the call.  All subsequent messages must be audio messages.
+func (s *AudioStream) sendConfig(cfg *juzupb.DiarizationConfig) error {
+	return s.stream.Send(&juzupb.StreamingDiarizeRequest{
+		Request: &juzupb.StreamingDiarizeRequest_Config{Config: cfg},
+	})
+}
+
+// receive passes the responses of the call to handlerFunc until the server
+// ends the call, and then records the error it ended with and closes done.
+func (s *AudioStream) receive(handlerFunc DiarizationResponseHandler) {
+	defer close(s.done)
+
+	if s.w.trimmer != nil {
+		handler, timeline := handlerFunc, s.w.trimmer.Timeline()
+		handlerFunc = func(resp *juzupb.DiarizationResponse) {
+			timeline.RemapResponse(resp)
+			handler(resp)
+		}
+	}
+
+	for {
+		in, err := s.stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		handlerFunc(in)
+	}
+}
+
+// Write sends the given audio to the server.  Audio is split into messages
+// of the Client's streaming buffer size, or buffered into chunks of the
+// configured chunk duration.  Empty writes are ignored and never reach the
+// server, where they could be mistaken for the end of the audio.
+//
+// After CloseAudio, or once the call has ended, Write returns io.EOF, and the
+// reason the call ended is returned by Wait.
+func (s *AudioStream) Write(p []byte) (int, error) {
+	if s.w.closed {
+		return 0, io.EOF
+	}
+	select {
+	case <-s.done:
+		return 0, io.EOF
+	default:
+	}
+	return s.w.Write(p)
+}
+
+// CloseAudio marks the end of the audio.  Any buffered audio is sent, and the
+// server is told that no more audio follows.  Calling CloseAudio more than
+// once has no further effect.
+func (s *AudioStream) CloseAudio() error {
+	return s.w.Close()
+}
+
+// Wait blocks until the server has ended the call and all responses have been
+// passed to the handler, and returns the error the call ended with, if any.
+// The server only ends the call after CloseAudio has been called, or after the
+// call's context has been cancelled.
+func (s *AudioStream) Wait() error {
+	<-s.done
+	if s.err != nil {
+		return fmt.Errorf("streaming diarization failed: %v", s.err)
+	}
+	return nil
+}
+
+// audioWriter is the path that audio takes to a stream.  Writes to it are
+// chunked and optionally encoded before being sent, and closing it marks the
+// end of the audio.  All streaming entry points send audio through an
+// audioWriter, which therefore is the single place that guarantees that no
+// empty DiarizationAudio message is ever sent.
+type audioWriter struct {
+	trimmer *vad.Trimmer
+	chunker *chunker
+	enc     *flac.Encoder
+	sender  *audioSender
+	closed  bool
+}
+
+func newAudioWriter(stream juzupb.Juzu_StreamingDiarizeClient, opts sendOptions) (*audioWriter, error) {
+	w := &audioWriter{sender: &audioSender{stream: stream, limiters: opts.limiters}}
+
+	var next io.Writer = w.sender
+	if opts.flacSampleRate != 0 {
+		enc, err := flac.NewEncoder(w.sender, int(opts.flacSampleRate), opts.flacBlockSize)
+		if err != nil {
+			return nil, err
+		}
+		w.enc = enc
+		next = enc
+	}
+
+	w.chunker = &chunker{next: next, size: int(opts.bufSize), full: opts.fullChunks}
+
+	if opts.trim != nil {
+		t, err := vad.NewTrimmer(w.chunker, int(opts.trimSampleRate), *opts.trim)
+		if err != nil {
+			return nil, err
+		}
+		w.trimmer = t
+	}
+	return w, nil
+}
+
+func (w *audioWriter) Write(p []byte) (int, error) {
+	if w.trimmer != nil {
+		return w.trimmer.Write(p)
+	}
+	return w.chunker.Write(p)
+}
+
+// Close sends any buffered audio, and then ends the audio with CloseSend.
+func (w *audioWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if w.trimmer != nil {
+		if err := w.trimmer.Close(); err != nil {
+			return err
+		}
+	}
+	if err := w.chunker.flush(); err != nil {
+		return err
+	}
+	if w.enc != nil {
+		if err := w.enc.Close(); err != nil {
+			return err
+		}
+	}
+	return w.sender.stream.CloseSend()
+}
+
+// chunker splits the data written to it into chunks of at most size bytes.
+// If full is set, data is buffered so that all chunks but the last one, sent
+// by flush, are exactly size bytes long.
+type chunker struct {
+	next io.Writer
+	size int
+	full bool
+	buf  []byte
+}
+
+func (c *chunker) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if !c.full {
+			m := len(p)
+			if m > c.size {
+				m = c.size
+			}
+			if _, err := c.next.Write(p[:m]); err != nil {
+				return 0, err
+			}
+			p = p[m:]
+			continue
+		}
+
+		m := c.size - len(c.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		c.buf = append(c.buf, p[:m]...)
+		p = p[m:]
+		if len(c.buf) == c.size {
+			if err := c.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// flush sends any buffered data as one chunk.
+func (c *chunker) flush() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	_, err := c.next.Write(c.buf)
+	c.buf = c.buf[:0]
+	return err
+}
+
+// audioSender is an io.Writer that sends the data of each Write call to a
+// stream as one DiarizationAudio message, waiting on the given limiters, in
+// order, before sending each message.  Empty writes are dropped, since the
+// server may take an empty message to be the end of the audio.
+type audioSender struct {
+	stream   juzupb.Juzu_StreamingDiarizeClient
+	limiters []*rateLimiter
+}
+
+func (s *audioSender) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	for _, l := range s.limiters {
+		if err := l.wait(s.stream.Context(), len(p)); err != nil {
+			// the call was cancelled while waiting, and the stream
+			// is already being torn down.
+			return 0, err
+		}
+	}
+
+	if err := s.stream.Send(&juzupb.StreamingDiarizeRequest{
+		Request: &juzupb.StreamingDiarizeRequest_Audio{
+			Audio: &juzupb.DiarizationAudio{Data: p},
+		},
+	}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,250 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package subtitle turns diarized transcripts into SRT and WebVTT subtitles,
+// with the speaker of each cue.
+//
+// Segments are split into cues at word boundaries, so that cues have a
+// limited number of lines of limited length, and a limited duration.  Cue
+// times come from the word timestamps of the segments; segments without
+// words have their time shared out between the words of their transcript.
+package subtitle
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Defaults used for zero fields of Options.
+const (
+	DefaultMaxLineLength = 42
+	DefaultMaxLines      = 2
+	DefaultMaxDuration   = 7 * time.Second
+)
+
+// SpeakerStyle sets how the speaker of each cue is shown.
+type SpeakerStyle int
+
+const (
+	// SpeakerPrefix starts the text of each cue with "speaker: ".
+	SpeakerPrefix SpeakerStyle = iota
+
+	// SpeakerVoice tags the text of WebVTT cues with the speaker, as in
+	// "<v speaker>text".  SRT has no voice tags, and uses SpeakerPrefix.
+	SpeakerVoice
+
+	// SpeakerNone does not show the speaker.
+	SpeakerNone
+)
+
+// Options configures how segments are split into cues.  Zero values select
+// the defaults.
+type Options struct {
+	// Maximum number of characters per line, and lines per cue.  A single
+	// word longer than a line gets a line of its own.
+	MaxLineLength int
+	MaxLines      int
+
+	// Maximum duration of a cue.  A single word longer than this gets a
+	// cue of its own.
+	MaxDuration time.Duration
+
+	Speaker SpeakerStyle
+
+	// Names, if set, maps speaker labels to the names shown.  Labels that
+	// it does not hold are shown as they are.
+	Names map[string]string
+}
+
+func (o *Options) setDefaults() {
+	if o.MaxLineLength <= 0 {
+		o.MaxLineLength = DefaultMaxLineLength
+	}
+	if o.MaxLines <= 0 {
+		o.MaxLines = DefaultMaxLines
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = DefaultMaxDuration
+	}
+}
+
+// Cue is a subtitle cue.
+type Cue struct {
+	Start, End time.Duration
+
+	// Speaker is the name of the speaker, or the label if it has no name.
+	Speaker string
+
+	// Lines of text, without the speaker.
+	Lines []string
+}
+
+// word is a word with its times.
+type word struct {
+	text       string
+	start, end time.Duration
+}
+
+// Cues splits the segments into cues.
+func Cues(segments []*juzupb.Segment, opts Options) []Cue {
+	opts.setDefaults()
+
+	var cues []Cue
+	for _, s := range segments {
+		speaker := s.SpeakerLabel
+		if name, ok := opts.Names[speaker]; ok {
+			speaker = name
+		}
+
+		// The speaker prefix takes room on the first line.
+		prefix := 0
+		if opts.Speaker == SpeakerPrefix && speaker != "" {
+			prefix = utf8.RuneCountInString(speaker) + 2
+		}
+
+		var cue *Cue
+		var line string
+		flush := func() {
+			if cue != nil {
+				cue.Lines = append(cue.Lines, line)
+			}
+		}
+
+		for _, w := range words(s) {
+			// add the word to the current line or to a new line of the
+			// current cue if they have room for it
+			if cue != nil && w.end-cue.Start <= opts.MaxDuration {
+				width := opts.MaxLineLength
+				if len(cue.Lines) == 0 {
+					width -= prefix
+				}
+				if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(w.text) <= width {
+					line += " " + w.text
+					cue.End = w.end
+					continue
+				}
+				if len(cue.Lines)+1 < opts.MaxLines {
+					cue.Lines = append(cue.Lines, line)
+					line = w.text
+					cue.End = w.end
+					continue
+				}
+			}
+
+			flush()
+			cues = append(cues, Cue{Start: w.start, End: w.end, Speaker: speaker})
+			cue = &cues[len(cues)-1]
+			line = w.text
+		}
+		flush()
+	}
+	return cues
+}
+
+// words returns the words of the segment with their times.  The text of the
+// words is taken from the transcript, which may be formatted, if it has as
+// many words as there are timestamps.
+func words(s *juzupb.Segment) []word {
+	text := strings.Fields(s.Transcript)
+
+	if len(s.Words) > 0 {
+		words := make([]word, len(s.Words))
+		for i, w := range s.Words {
+			start := w.StartTime.AsDuration()
+			words[i] = word{text: w.Word, start: start, end: start + w.Duration.AsDuration()}
+			if len(text) == len(s.Words) {
+				words[i].text = text[i]
+			}
+		}
+		return words
+	}
+
+	// Share out the time of the segment in proportion to the length of
+	// the words, counting a space after each.
+	total := 0
+	for _, t := range text {
+		total += utf8.RuneCountInString(t) + 1
+	}
+	start, end := s.StartTime.AsDuration(), s.EndTime.AsDuration()
+	words := make([]word, len(text))
+	n := 0
+	for i, t := range text {
+		words[i].text = t
+		words[i].start = start + (end-start)*time.Duration(n)/time.Duration(total)
+		n += utf8.RuneCountInString(t) + 1
+		words[i].end = start + (end-start)*time.Duration(n-1)/time.Duration(total)
+	}
+	return words
+}
+
+// WriteSRT writes the segments as SRT subtitles.
+func WriteSRT(w io.Writer, segments []*juzupb.Segment, opts Options) error {
+	if opts.Speaker == SpeakerVoice {
+		opts.Speaker = SpeakerPrefix
+	}
+	for i, c := range Cues(segments, opts) {
+		text := strings.Join(c.Lines, "\n")
+		if opts.Speaker != SpeakerNone && c.Speaker != "" {
+			text = c.Speaker + ": " + text
+		}
+		if _, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1,
+			formatTime(c.Start, ','), formatTime(c.End, ','), text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteVTT writes the segments as WebVTT subtitles.
+func WriteVTT(w io.Writer, segments []*juzupb.Segment, opts Options) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+
+	for _, c := range Cues(segments, opts) {
+		text := escapeVTT(strings.Join(c.Lines, "\n"))
+		if c.Speaker != "" {
+			switch opts.Speaker {
+			case SpeakerPrefix:
+				text = escapeVTT(c.Speaker) + ": " + text
+			case SpeakerVoice:
+				text = "<v " + escapeVTT(c.Speaker) + ">" + text
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s --> %s\n%s\n\n",
+			formatTime(c.Start, '.'), formatTime(c.End, '.'), text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeVTT escapes the characters that have a meaning in WebVTT cue text.
+func escapeVTT(s string) string {
+	return vttEscaper.Replace(s)
+}
+
+// formatTime formats t as hh:mm:ss followed by the separator and
+// milliseconds.
+func formatTime(t time.Duration, sep byte) string {
+	ms := t.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,106 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subtitle_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/subtitle"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// segment returns a segment with words of 500ms each from the given start.
+func segment(label string, start time.Duration, transcript string) *juzupb.Segment {
+	s := &juzupb.Segment{SpeakerLabel: label, StartTime: durationpb.New(start), Transcript: transcript}
+	for _, w := range strings.Fields(strings.ToLower(transcript)) {
+		s.Words = append(s.Words, &juzupb.WordInfo{
+			Word:      strings.Trim(w, ".,"),
+			StartTime: durationpb.New(start),
+			Duration:  durationpb.New(500 * time.Millisecond),
+		})
+		start += 500 * time.Millisecond
+	}
+	s.EndTime = durationpb.New(start)
+	return s
+}
+
+func TestSRT(t *testing.T) {
+	segs := []*juzupb.Segment{
+		segment("0", 0, "Hello there, how are you doing today?"),
+		segment("1", 4*time.Second, "Fine. And you? I have been waiting for this call for a long time now."),
+	}
+
+	var buf bytes.Buffer
+	opts := subtitle.Options{MaxLineLength: 20, MaxDuration: 4 * time.Second, Names: map[string]string{"1": "Bob"}}
+	if err := subtitle.WriteSRT(&buf, segs, opts); err != nil {
+		t.Fatalf("could not write SRT: %v", err)
+	}
+
+	want := `1
+00:00:00,000 --> 00:00:03,500
+0: Hello there, how
+are you doing today?
+
+2
+00:00:04,000 --> 00:00:07,500
+Bob: Fine. And you?
+I have been waiting
+
+3
+00:00:07,500 --> 00:00:11,500
+Bob: for this call
+for a long time now.
+
+`
+	if buf.String() != want {
+		t.Errorf("wrote SRT\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestVTT(t *testing.T) {
+	// without words, times are shared out by word length
+	segs := []*juzupb.Segment{{
+		SpeakerLabel: "<A>",
+		StartTime:    durationpb.New(time.Hour),
+		EndTime:      durationpb.New(time.Hour + 8*time.Second),
+		Transcript:   "one two three four",
+	}}
+
+	var buf bytes.Buffer
+	opts := subtitle.Options{MaxLineLength: 9, MaxLines: 1, Speaker: subtitle.SpeakerVoice}
+	if err := subtitle.WriteVTT(&buf, segs, opts); err != nil {
+		t.Fatalf("could not write WebVTT: %v", err)
+	}
+
+	want := `WEBVTT
+
+01:00:00.000 --> 01:00:02.947
+<v &lt;A&gt;>one two
+
+01:00:03.368 --> 01:00:05.473
+<v &lt;A&gt;>three
+
+01:00:05.894 --> 01:00:07.578
+<v &lt;A&gt;>four
+
+`
+	if buf.String() != want {
+		t.Errorf("wrote WebVTT\n%s\nwant\n%s", buf.String(), want)
+	}
+}
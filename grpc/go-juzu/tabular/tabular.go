@@ -0,0 +1,240 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tabular exports diarization results as flat rows, in CSV or JSON
+// lines, to load them into databases and data warehouses.
+//
+// Rows describe either segments or words, with the following columns, in this
+// order.  Times are in seconds from the start of the audio.  The confidence
+// is empty in CSV, and null in JSON, when there is none.
+//
+// Segment rows:
+//
+//	id          string  ID of the audio file or job, given by the caller
+//	segment     int     index of the segment in the results, from 0
+//	speaker     string  speaker label
+//	start       float   start time
+//	end         float   end time
+//	transcript  string  transcript
+//	confidence  float   mean confidence of the words of the segment
+//	words       int     number of words of the segment
+//
+// Word rows:
+//
+//	id          string  ID of the audio file or job, given by the caller
+//	segment     int     index of the segment of the word in the results
+//	index       int     index of the word in its segment, from 0
+//	speaker     string  speaker label
+//	start       float   start time
+//	end         float   end time
+//	word        string  word
+//	confidence  float   confidence of the word
+//
+// In JSON lines, each row is an object with the column names as keys.  CSV
+// files start with a header of the column names.
+package tabular
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/confidence"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Level sets what rows describe.
+type Level int
+
+const (
+	// Segments writes a row per segment.
+	Segments Level = iota
+
+	// Words writes a row per word.  Segments without words have no rows.
+	Words
+)
+
+// Columns of the rows of each level.
+var (
+	SegmentColumns = []string{"id", "segment", "speaker", "start", "end", "transcript", "confidence", "words"}
+	WordColumns    = []string{"id", "segment", "index", "speaker", "start", "end", "word", "confidence"}
+)
+
+// SegmentRow is a row describing a segment.
+type SegmentRow struct {
+	ID         string   `json:"id"`
+	Segment    int      `json:"segment"`
+	Speaker    string   `json:"speaker"`
+	Start      float64  `json:"start"`
+	End        float64  `json:"end"`
+	Transcript string   `json:"transcript"`
+	Confidence *float64 `json:"confidence"`
+	Words      int      `json:"words"`
+}
+
+// WordRow is a row describing a word.
+type WordRow struct {
+	ID         string   `json:"id"`
+	Segment    int      `json:"segment"`
+	Index      int      `json:"index"`
+	Speaker    string   `json:"speaker"`
+	Start      float64  `json:"start"`
+	End        float64  `json:"end"`
+	Word       string   `json:"word"`
+	Confidence *float64 `json:"confidence"`
+}
+
+// SegmentRows returns the rows of the segments of the results.
+func SegmentRows(id string, results []*juzupb.DiarizationResult) []SegmentRow {
+	var rows []SegmentRow
+	for _, r := range results {
+		for _, s := range r.Segments {
+			row := SegmentRow{
+				ID:         id,
+				Segment:    len(rows),
+				Speaker:    s.SpeakerLabel,
+				Start:      s.StartTime.AsDuration().Seconds(),
+				End:        s.EndTime.AsDuration().Seconds(),
+				Transcript: s.Transcript,
+				Words:      len(s.Words),
+			}
+			if conf, ok := confidence.Average(s.Words); ok {
+				row.Confidence = &conf
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// WordRows returns the rows of the words of the results.
+func WordRows(id string, results []*juzupb.DiarizationResult) []WordRow {
+	var rows []WordRow
+	segment := 0
+	for _, r := range results {
+		for _, s := range r.Segments {
+			for i, w := range s.Words {
+				start := w.StartTime.AsDuration()
+				conf := w.Confidence
+				rows = append(rows, WordRow{
+					ID:         id,
+					Segment:    segment,
+					Index:      i,
+					Speaker:    s.SpeakerLabel,
+					Start:      start.Seconds(),
+					End:        (start + w.Duration.AsDuration()).Seconds(),
+					Word:       w.Word,
+					Confidence: &conf,
+				})
+			}
+			segment++
+		}
+	}
+	return rows
+}
+
+// Writer writes rows of results in CSV or JSON lines.
+type Writer struct {
+	level Level
+
+	// one of the two is set
+	csv  *csv.Writer
+	json *json.Encoder
+
+	wroteHeader bool
+}
+
+// NewCSVWriter returns a Writer of CSV rows of the given level to w.  The
+// header is written with the first rows.
+func NewCSVWriter(w io.Writer, level Level) *Writer {
+	return &Writer{level: level, csv: csv.NewWriter(w)}
+}
+
+// NewJSONLinesWriter returns a Writer of JSON lines of rows of the given
+// level to w.
+func NewJSONLinesWriter(w io.Writer, level Level) *Writer {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &Writer{level: level, json: enc}
+}
+
+// Write writes the rows of the results, with the given id.  Segments are
+// numbered from 0 in each call.
+func (w *Writer) Write(id string, results []*juzupb.DiarizationResult) error {
+	if w.json != nil {
+		return w.writeJSON(id, results)
+	}
+	return w.writeCSV(id, results)
+}
+
+func (w *Writer) writeJSON(id string, results []*juzupb.DiarizationResult) error {
+	switch w.level {
+	case Segments:
+		for _, row := range SegmentRows(id, results) {
+			if err := w.json.Encode(row); err != nil {
+				return err
+			}
+		}
+	case Words:
+		for _, row := range WordRows(id, results) {
+			if err := w.json.Encode(row); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("invalid level %d", w.level)
+	}
+	return nil
+}
+
+func (w *Writer) writeCSV(id string, results []*juzupb.DiarizationResult) error {
+	var records [][]string
+	switch w.level {
+	case Segments:
+		if !w.wroteHeader {
+			records = append(records, SegmentColumns)
+		}
+		for _, r := range SegmentRows(id, results) {
+			records = append(records, []string{r.ID, strconv.Itoa(r.Segment), r.Speaker,
+				formatFloat(r.Start), formatFloat(r.End), r.Transcript, formatConfidence(r.Confidence),
+				strconv.Itoa(r.Words)})
+		}
+	case Words:
+		if !w.wroteHeader {
+			records = append(records, WordColumns)
+		}
+		for _, r := range WordRows(id, results) {
+			records = append(records, []string{r.ID, strconv.Itoa(r.Segment), strconv.Itoa(r.Index),
+				r.Speaker, formatFloat(r.Start), formatFloat(r.End), r.Word, formatConfidence(r.Confidence)})
+		}
+	default:
+		return fmt.Errorf("invalid level %d", w.level)
+	}
+	w.wroteHeader = true
+
+	return w.csv.WriteAll(records)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatConfidence(c *float64) string {
+	if c == nil {
+		return ""
+	}
+	return formatFloat(*c)
+}
@@ -0,0 +1,129 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tabular_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/tabular"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func word(w string, start, dur time.Duration, conf float64) *juzupb.WordInfo {
+	return &juzupb.WordInfo{Word: w, StartTime: durationpb.New(start), Duration: durationpb.New(dur), Confidence: conf}
+}
+
+var results = []*juzupb.DiarizationResult{
+	{Segments: []*juzupb.Segment{
+		{
+			SpeakerLabel: "1",
+			StartTime:    durationpb.New(500 * time.Millisecond),
+			EndTime:      durationpb.New(1500 * time.Millisecond),
+			Transcript:   `hello, "there"`,
+			Words: []*juzupb.WordInfo{
+				word("hello", 500*time.Millisecond, 400*time.Millisecond, 0.5),
+				word("there", time.Second, 500*time.Millisecond, 1),
+			},
+		},
+	}},
+	{Segments: []*juzupb.Segment{
+		{
+			SpeakerLabel: "2",
+			StartTime:    durationpb.New(2 * time.Second),
+			EndTime:      durationpb.New(2250 * time.Millisecond),
+			Transcript:   "<hm>",
+		},
+	}},
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   bool
+		level tabular.Level
+		want  string
+	}{
+		{
+			name:  "csv segments",
+			csv:   true,
+			level: tabular.Segments,
+			want: `id,segment,speaker,start,end,transcript,confidence,words
+a,0,1,0.5,1.5,"hello, ""there""",0.75,2
+a,1,2,2,2.25,<hm>,,0
+b,0,1,0.5,1.5,"hello, ""there""",0.75,2
+b,1,2,2,2.25,<hm>,,0
+`,
+		},
+		{
+			name:  "csv words",
+			csv:   true,
+			level: tabular.Words,
+			want: `id,segment,index,speaker,start,end,word,confidence
+a,0,0,1,0.5,0.9,hello,0.5
+a,0,1,1,1,1.5,there,1
+b,0,0,1,0.5,0.9,hello,0.5
+b,0,1,1,1,1.5,there,1
+`,
+		},
+		{
+			name:  "jsonl segments",
+			level: tabular.Segments,
+			want: `{"id":"a","segment":0,"speaker":"1","start":0.5,"end":1.5,"transcript":"hello, \"there\"","confidence":0.75,"words":2}
+{"id":"a","segment":1,"speaker":"2","start":2,"end":2.25,"transcript":"<hm>","confidence":null,"words":0}
+{"id":"b","segment":0,"speaker":"1","start":0.5,"end":1.5,"transcript":"hello, \"there\"","confidence":0.75,"words":2}
+{"id":"b","segment":1,"speaker":"2","start":2,"end":2.25,"transcript":"<hm>","confidence":null,"words":0}
+`,
+		},
+		{
+			name:  "jsonl words",
+			level: tabular.Words,
+			want: `{"id":"a","segment":0,"index":0,"speaker":"1","start":0.5,"end":0.9,"word":"hello","confidence":0.5}
+{"id":"a","segment":0,"index":1,"speaker":"1","start":1,"end":1.5,"word":"there","confidence":1}
+{"id":"b","segment":0,"index":0,"speaker":"1","start":0.5,"end":0.9,"word":"hello","confidence":0.5}
+{"id":"b","segment":0,"index":1,"speaker":"1","start":1,"end":1.5,"word":"there","confidence":1}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var w *tabular.Writer
+			if tt.csv {
+				w = tabular.NewCSVWriter(&buf, tt.level)
+			} else {
+				w = tabular.NewJSONLinesWriter(&buf, tt.level)
+			}
+			for _, id := range []string{"a", "b"} {
+				if err := w.Write(id, results); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriterInvalidLevel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := tabular.NewCSVWriter(&buf, tabular.Level(5)).Write("a", results); err == nil {
+		t.Error("got no error for an invalid level")
+	}
+}
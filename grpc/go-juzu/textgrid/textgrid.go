@@ -0,0 +1,357 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package textgrid writes and reads diarization segments as Praat TextGrids.
+//
+// Each speaker has an interval tier of their segments, named after their
+// label, with the transcripts as text, and, if the segments have words, an
+// interval tier of their words, named after their label followed by "/words".
+// Praat tiers cover the whole audio, so the times between annotations are
+// written as intervals with empty text, and intervals with empty text are
+// skipped when reading.  Word confidences are not kept.
+package textgrid
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/internal/tier"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Write writes the segments as a TextGrid in the long text format, for audio
+// of the given duration.  If the segments end after the duration, the
+// TextGrid ends with them.  Overlapping intervals of a tier, which Praat does
+// not allow, are cut to start at the end of the previous one.
+func Write(w io.Writer, segments []*juzupb.Segment, duration time.Duration) error {
+	tiers := tier.FromSegments(segments)
+	for _, t := range tiers {
+		for _, iv := range t.Intervals {
+			if iv.End > duration {
+				duration = iv.End
+			}
+		}
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "File type = \"ooTextFile\"\nObject class = \"TextGrid\"\n\n")
+	fmt.Fprintf(b, "xmin = 0 \nxmax = %s \n", seconds(duration))
+	if len(tiers) == 0 {
+		fmt.Fprintf(b, "tiers? <absent> \n")
+		return b.Flush()
+	}
+	fmt.Fprintf(b, "tiers? <exists> \nsize = %d \nitem []: \n", len(tiers))
+	for i, t := range tiers {
+		intervals := fill(t.Intervals, duration)
+		fmt.Fprintf(b, "    item [%d]:\n", i+1)
+		fmt.Fprintf(b, "        class = \"IntervalTier\" \n")
+		fmt.Fprintf(b, "        name = %s \n", quote(t.Name))
+		fmt.Fprintf(b, "        xmin = 0 \n        xmax = %s \n", seconds(duration))
+		fmt.Fprintf(b, "        intervals: size = %d \n", len(intervals))
+		for j, iv := range intervals {
+			fmt.Fprintf(b, "        intervals [%d]:\n", j+1)
+			fmt.Fprintf(b, "            xmin = %s \n", seconds(iv.Start))
+			fmt.Fprintf(b, "            xmax = %s \n", seconds(iv.End))
+			fmt.Fprintf(b, "            text = %s \n", quote(iv.Text))
+		}
+	}
+	return b.Flush()
+}
+
+// fill returns the intervals, without overlaps, with empty intervals added
+// so that they cover the time from 0 to the duration.
+func fill(intervals []tier.Interval, duration time.Duration) []tier.Interval {
+	var out []tier.Interval
+	var last time.Duration
+	for _, iv := range intervals {
+		if iv.Start < last {
+			iv.Start = last
+		}
+		if iv.End <= iv.Start {
+			continue
+		}
+		if iv.Start > last {
+			out = append(out, tier.Interval{Start: last, End: iv.Start})
+		}
+		out = append(out, iv)
+		last = iv.End
+	}
+	if last < duration || len(out) == 0 {
+		out = append(out, tier.Interval{Start: last, End: duration})
+	}
+	return out
+}
+
+// seconds formats d in seconds.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// quote returns s as a Praat string, with quotes doubled.
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// Read parses a TextGrid, in the long or short text format, encoded in UTF-8
+// or, as Praat writes files with non-ASCII text, in UTF-16 with a byte order
+// mark, and returns its segments sorted by start time.  Words are added to the
+// segment of their speaker they overlap the most.  Point tiers are skipped.
+func Read(r io.Reader) ([]*juzupb.Segment, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{text: text}
+	if s, err := p.string(); err != nil || s != "ooTextFile" {
+		return nil, errors.New("not a Praat text file")
+	}
+	if s, err := p.string(); err != nil || s != "TextGrid" {
+		return nil, errors.New("not a TextGrid")
+	}
+	if _, err := p.number(); err != nil {
+		return nil, err
+	}
+	if _, err := p.number(); err != nil {
+		return nil, err
+	}
+	exists, err := p.flag()
+	if err != nil || !exists {
+		return nil, err
+	}
+	n, err := p.count()
+	if err != nil {
+		return nil, err
+	}
+
+	var tiers []*tier.Tier
+	for i := 0; i < n; i++ {
+		class, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		name, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.number(); err != nil {
+			return nil, err
+		}
+		if _, err := p.number(); err != nil {
+			return nil, err
+		}
+		size, err := p.count()
+		if err != nil {
+			return nil, err
+		}
+
+		switch class {
+		case "IntervalTier":
+			t := &tier.Tier{Name: name}
+			for j := 0; j < size; j++ {
+				start, err := p.time()
+				if err != nil {
+					return nil, err
+				}
+				end, err := p.time()
+				if err != nil {
+					return nil, err
+				}
+				text, err := p.string()
+				if err != nil {
+					return nil, err
+				}
+				t.Intervals = append(t.Intervals, tier.Interval{Start: start, End: end, Text: text})
+			}
+			tiers = append(tiers, t)
+		case "TextTier":
+			for j := 0; j < size; j++ {
+				if _, err := p.number(); err != nil {
+					return nil, err
+				}
+				if _, err := p.string(); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown tier class %q", class)
+		}
+	}
+	return tier.ToSegments(tiers), nil
+}
+
+// decode returns the text of the data, decoded from UTF-8 or UTF-16.
+func decode(data []byte) (string, error) {
+	var order func([]byte) uint16
+	switch {
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		order = func(b []byte) uint16 { return uint16(b[0])<<8 | uint16(b[1]) }
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		order = func(b []byte) uint16 { return uint16(b[1])<<8 | uint16(b[0]) }
+	default:
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+		if !utf8.Valid(data) {
+			return "", errors.New("invalid UTF-8 text")
+		}
+		return string(data), nil
+	}
+
+	data = data[2:]
+	if len(data)%2 != 0 {
+		return "", errors.New("invalid UTF-16 text")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order(data[2*i:])
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// parser reads the values of a Praat text file, skipping the labels of the
+// long format, such as "xmin =" and "item [1]:", and comments, which start
+// with "!" and run to the end of the line.
+type parser struct {
+	text string
+	pos  int
+}
+
+// token returns the next value, as it is written.
+func (p *parser) token() (string, error) {
+	for p.pos < len(p.text) {
+		c := p.text[p.pos]
+		switch {
+		case c == '"':
+			start := p.pos
+			p.pos++
+			for {
+				i := strings.IndexByte(p.text[p.pos:], '"')
+				if i < 0 {
+					return "", errors.New("unterminated string")
+				}
+				p.pos += i + 1
+				if p.pos < len(p.text) && p.text[p.pos] == '"' {
+					p.pos++
+					continue
+				}
+				return p.text[start:p.pos], nil
+			}
+		case c == '!':
+			if i := strings.IndexByte(p.text[p.pos:], '\n'); i >= 0 {
+				p.pos += i
+			} else {
+				p.pos = len(p.text)
+			}
+		case c == '[':
+			if i := strings.IndexByte(p.text[p.pos:], ']'); i >= 0 {
+				p.pos += i + 1
+			} else {
+				p.pos = len(p.text)
+			}
+		case c == '<' || c == '-' || c == '+' || c == '.' || c >= '0' && c <= '9':
+			start := p.pos
+			for p.pos < len(p.text) && !isSpace(p.text[p.pos]) {
+				p.pos++
+			}
+			return p.text[start:p.pos], nil
+		default:
+			// skip labels and whitespace
+			for p.pos < len(p.text) && !isSpace(p.text[p.pos]) && p.text[p.pos] != '"' {
+				p.pos++
+			}
+			for p.pos < len(p.text) && isSpace(p.text[p.pos]) {
+				p.pos++
+			}
+		}
+	}
+	return "", io.ErrUnexpectedEOF
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// string reads a string.
+func (p *parser) string() (string, error) {
+	tok, err := p.token()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(tok, `"`) {
+		return "", fmt.Errorf("got %q; want a string", tok)
+	}
+	return strings.ReplaceAll(tok[1:len(tok)-1], `""`, `"`), nil
+}
+
+// number reads a number.
+func (p *parser) number() (float64, error) {
+	tok, err := p.token()
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(tok, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("got %q; want a number", tok)
+	}
+	return f, nil
+}
+
+// time reads a time in seconds, rounded to the nanosecond.
+func (p *parser) time() (time.Duration, error) {
+	f, err := p.number()
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(math.Round(f * float64(time.Second))), nil
+}
+
+// count reads a non-negative integer.
+func (p *parser) count() (int, error) {
+	f, err := p.number()
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 || f != math.Trunc(f) {
+		return 0, fmt.Errorf("invalid count %v", f)
+	}
+	return int(f), nil
+}
+
+// flag reads a flag, written as <exists> or <absent>.
+func (p *parser) flag() (bool, error) {
+	tok, err := p.token()
+	if err != nil {
+		return false, err
+	}
+	switch tok {
+	case "<exists>":
+		return true, nil
+	case "<absent>":
+		return false, nil
+	}
+	return false, fmt.Errorf("got %q; want <exists> or <absent>", tok)
+}
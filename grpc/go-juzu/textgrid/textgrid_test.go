@@ -0,0 +1,248 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package textgrid_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/textgrid"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func ms(n int) *durationpb.Duration {
+	return durationpb.New(time.Duration(n) * time.Millisecond)
+}
+
+var segments = []*juzupb.Segment{
+	{
+		SpeakerLabel: "1",
+		StartTime:    ms(500),
+		EndTime:      ms(2000),
+		Transcript:   `say "hi"`,
+		Words: []*juzupb.WordInfo{
+			{Word: "say", StartTime: ms(500), Duration: ms(500)},
+			{Word: "hi", StartTime: ms(1000), Duration: ms(1000)},
+		},
+	},
+	{SpeakerLabel: "2", StartTime: ms(1500), EndTime: ms(3000), Transcript: "yes"},
+}
+
+const want = `File type = "ooTextFile"
+Object class = "TextGrid"
+
+xmin = 0 
+xmax = 4 
+tiers? <exists> 
+size = 3 
+item []: 
+    item [1]:
+        class = "IntervalTier" 
+        name = "1" 
+        xmin = 0 
+        xmax = 4 
+        intervals: size = 3 
+        intervals [1]:
+            xmin = 0 
+            xmax = 0.5 
+            text = "" 
+        intervals [2]:
+            xmin = 0.5 
+            xmax = 2 
+            text = "say ""hi""" 
+        intervals [3]:
+            xmin = 2 
+            xmax = 4 
+            text = "" 
+    item [2]:
+        class = "IntervalTier" 
+        name = "1/words" 
+        xmin = 0 
+        xmax = 4 
+        intervals: size = 4 
+        intervals [1]:
+            xmin = 0 
+            xmax = 0.5 
+            text = "" 
+        intervals [2]:
+            xmin = 0.5 
+            xmax = 1 
+            text = "say" 
+        intervals [3]:
+            xmin = 1 
+            xmax = 2 
+            text = "hi" 
+        intervals [4]:
+            xmin = 2 
+            xmax = 4 
+            text = "" 
+    item [3]:
+        class = "IntervalTier" 
+        name = "2" 
+        xmin = 0 
+        xmax = 4 
+        intervals: size = 3 
+        intervals [1]:
+            xmin = 0 
+            xmax = 1.5 
+            text = "" 
+        intervals [2]:
+            xmin = 1.5 
+            xmax = 3 
+            text = "yes" 
+        intervals [3]:
+            xmin = 3 
+            xmax = 4 
+            text = "" 
+`
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	if err := textgrid.Write(&b, segments, 4*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteOverlap(t *testing.T) {
+	segs := []*juzupb.Segment{
+		{SpeakerLabel: "1", StartTime: ms(0), EndTime: ms(1000), Transcript: "a"},
+		{SpeakerLabel: "1", StartTime: ms(800), EndTime: ms(1500), Transcript: "b"},
+	}
+	var b bytes.Buffer
+	if err := textgrid.Write(&b, segs, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, err := textgrid.Read(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSegs := []*juzupb.Segment{
+		{SpeakerLabel: "1", StartTime: ms(0), EndTime: ms(1000), Transcript: "a"},
+		{SpeakerLabel: "1", StartTime: ms(1000), EndTime: ms(1500), Transcript: "b"},
+	}
+	checkSegments(t, got, wantSegs)
+}
+
+func TestRead(t *testing.T) {
+	short := `File type = "ooTextFile"
+Object class = "TextGrid"
+
+0
+4
+<exists>
+3
+"IntervalTier"
+"2"
+0
+4
+2
+0
+1.5
+""
+1.5
+3
+"yes"
+"TextTier"
+"events"
+0
+4
+1
+1
+"cough"
+"IntervalTier"
+"1"
+0
+4
+1
+0.5
+2
+"say ""hi"""
+`
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"long", []byte(want)},
+		{"short", []byte(short)},
+		{"utf-16", utf16BE(want)},
+		{"utf-8 with bom", append([]byte("\xef\xbb\xbf"), want...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := textgrid.Read(bytes.NewReader(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantSegs := segments
+			if tt.name == "short" {
+				wantSegs = []*juzupb.Segment{
+					{SpeakerLabel: "1", StartTime: ms(500), EndTime: ms(2000), Transcript: `say "hi"`},
+					segments[1],
+				}
+			}
+			checkSegments(t, got, wantSegs)
+		})
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not praat", "hello"},
+		{"not textgrid", `File type = "ooTextFile"` + "\n" + `Object class = "Pitch 1"`},
+		{"truncated", want[:len(want)/2]},
+		{"bad number", strings.Replace(want, "xmax = 0.5", "xmax = x", 1)},
+		{"unterminated", want[:strings.Index(want, `"say`)+3]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := textgrid.Read(strings.NewReader(tt.data)); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+func utf16BE(s string) []byte {
+	b := []byte{0xfe, 0xff}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b
+}
+
+func checkSegments(t *testing.T, got, want []*juzupb.Segment) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d segments; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Errorf("segment %d: got %v; want %v", i, got[i], want[i])
+		}
+	}
+}
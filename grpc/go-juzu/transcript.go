@@ -0,0 +1,186 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+)
+
+// Accumulator collects the results of a diarization as responses are
+// received, and consolidates them into a Transcript.
+//
+// Final results are kept in the order they are received.  A partial result
+// stands for the audio that has not been finalized yet, and is superseded by
+// the next result received, partial or final; only the latest partial result
+// is kept.
+//
+// The zero value is ready to use, and its Handle method can be given to
+// StreamingDiarize or NewAudioStream as the response handler:
+//
+//	var acc juzu.Accumulator
+//	err := client.StreamingDiarize(ctx, cfg, audio, acc.Handle)
+//	...
+//	for _, seg := range acc.Transcript().Segments {
+//		...
+//	}
+//
+// An Accumulator is safe for concurrent use.
+type Accumulator struct {
+	mu      sync.Mutex
+	final   []*juzupb.DiarizationResult
+	partial *juzupb.DiarizationResult
+}
+
+// Handle adds the results of the response.
+func (a *Accumulator) Handle(resp *juzupb.DiarizationResponse) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, r := range resp.Results {
+		if r.IsPartial {
+			a.partial = r
+			continue
+		}
+		a.final = append(a.final, r)
+		a.partial = nil
+	}
+}
+
+// Partial returns the latest partial result if no result has superseded it
+// yet, or nil.
+func (a *Accumulator) Partial() *juzupb.DiarizationResult {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.partial
+}
+
+// Transcript returns the consolidation of the final results received so far.
+func (a *Accumulator) Transcript() *Transcript {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return NewTranscript(a.final)
+}
+
+// Transcript is the consolidation of several diarization results.  Its
+// segments must not be modified.
+type Transcript struct {
+	// Segments of all results, ordered by start time, and then by end time.
+	Segments []*juzupb.Segment
+
+	// Speakers holds the speaker labels in order of first speech, followed
+	// by the labels listed in the results that no segment has.
+	Speakers []string
+
+	// Overlaps holds the overlapping speech regions marked in the results,
+	// in order, or those found by Overlaps if no result has any.
+	Overlaps []*juzupb.OverlapRegion
+}
+
+// NewTranscript consolidates the given results, regardless of whether they
+// are partial.
+func NewTranscript(results []*juzupb.DiarizationResult) *Transcript {
+	t := &Transcript{}
+	for _, r := range results {
+		t.Segments = append(t.Segments, r.Segments...)
+	}
+	sort.SliceStable(t.Segments, func(i, j int) bool {
+		si, sj := t.Segments[i], t.Segments[j]
+		if a, b := si.StartTime.AsDuration(), sj.StartTime.AsDuration(); a != b {
+			return a < b
+		}
+		return si.EndTime.AsDuration() < sj.EndTime.AsDuration()
+	})
+
+	seen := make(map[string]bool)
+	addSpeaker := func(label string) {
+		if !seen[label] {
+			seen[label] = true
+			t.Speakers = append(t.Speakers, label)
+		}
+	}
+	for _, s := range t.Segments {
+		addSpeaker(s.SpeakerLabel)
+	}
+	for _, r := range results {
+		for _, label := range r.SpeakerLabels {
+			addSpeaker(label)
+		}
+	}
+
+	for _, r := range results {
+		t.Overlaps = append(t.Overlaps, r.Overlaps...)
+	}
+	if len(t.Overlaps) == 0 {
+		t.Overlaps = Overlaps(t.Segments)
+	}
+	sort.SliceStable(t.Overlaps, func(i, j int) bool {
+		return t.Overlaps[i].StartTime.AsDuration() < t.Overlaps[j].StartTime.AsDuration()
+	})
+	return t
+}
+
+// Result returns the transcript as a single final result.
+func (t *Transcript) Result() *juzupb.DiarizationResult {
+	return &juzupb.DiarizationResult{Segments: t.Segments, SpeakerLabels: t.Speakers, Overlaps: t.Overlaps}
+}
+
+// BySpeaker returns the segments of the given speaker, in order.
+func (t *Transcript) BySpeaker(label string) []*juzupb.Segment {
+	var segs []*juzupb.Segment
+	for _, s := range t.Segments {
+		if s.SpeakerLabel == label {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+// Between returns the segments that overlap the time range [start, end), in
+// order.
+func (t *Transcript) Between(start, end time.Duration) []*juzupb.Segment {
+	var segs []*juzupb.Segment
+	for _, s := range t.Segments {
+		if s.StartTime.AsDuration() >= end {
+			break
+		}
+		if s.EndTime.AsDuration() > start {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+// At returns the segments that hold the given time, which are those of the
+// speakers talking at that time.
+func (t *Transcript) At(at time.Duration) []*juzupb.Segment {
+	return t.Between(at, at+1)
+}
+
+// Text returns the transcripts of the segments of all speakers, in order,
+// separated by spaces.
+func (t *Transcript) Text() string {
+	var text []string
+	for _, s := range t.Segments {
+		if s.Transcript != "" {
+			text = append(text, s.Transcript)
+		}
+	}
+	return strings.Join(text, " ")
+}
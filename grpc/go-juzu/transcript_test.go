@@ -0,0 +1,131 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package juzu_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	juzu "github.com/cobaltspeech/sdk-juzu/grpc/go-juzu"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func segment(label string, start, end time.Duration, text string) *juzupb.Segment {
+	return &juzupb.Segment{
+		SpeakerLabel: label,
+		StartTime:    durationpb.New(start),
+		EndTime:      durationpb.New(end),
+		Transcript:   text,
+	}
+}
+
+func segmentTexts(segs []*juzupb.Segment) []string {
+	var texts []string
+	for _, s := range segs {
+		texts = append(texts, s.Transcript)
+	}
+	return texts
+}
+
+func TestAccumulator(t *testing.T) {
+	var acc juzu.Accumulator
+
+	// Results may arrive out of order, and partial results are replaced by
+	// the results that follow them.
+	acc.Handle(&juzupb.DiarizationResponse{Results: []*juzupb.DiarizationResult{{
+		Segments:  []*juzupb.Segment{segment("1", 0, time.Second, "partial")},
+		IsPartial: true,
+	}}})
+	if p := acc.Partial(); p == nil || len(acc.Transcript().Segments) != 0 {
+		t.Errorf("partial result: got partial %v and transcript %v", p, acc.Transcript().Segments)
+	}
+
+	acc.Handle(&juzupb.DiarizationResponse{Results: []*juzupb.DiarizationResult{{
+		Segments: []*juzupb.Segment{
+			segment("2", 3*time.Second, 5*time.Second, "c"),
+			segment("1", 0, 2*time.Second, "a"),
+		},
+		SpeakerLabels: []string{"1", "2", "3"},
+	}, {
+		Segments:  []*juzupb.Segment{segment("1", 5*time.Second, 6*time.Second, "partial")},
+		IsPartial: true,
+	}}})
+	acc.Handle(&juzupb.DiarizationResponse{Results: []*juzupb.DiarizationResult{{
+		Segments: []*juzupb.Segment{
+			segment("2", time.Second, 3*time.Second, "b"),
+			segment("1", 5*time.Second, 7*time.Second, "d"),
+		},
+	}}})
+	if p := acc.Partial(); p != nil {
+		t.Errorf("superseded partial result %v", p)
+	}
+
+	tr := acc.Transcript()
+	for _, c := range []struct {
+		what      string
+		got, want []string
+	}{
+		{"segments", segmentTexts(tr.Segments), []string{"a", "b", "c", "d"}},
+		{"speakers", tr.Speakers, []string{"1", "2", "3"}},
+		{"speaker 1", segmentTexts(tr.BySpeaker("1")), []string{"a", "d"}},
+		{"speaker 3", segmentTexts(tr.BySpeaker("3")), nil},
+		{"between 2s and 5s", segmentTexts(tr.Between(2*time.Second, 5*time.Second)), []string{"b", "c"}},
+		{"at 1.5s", segmentTexts(tr.At(1500 * time.Millisecond)), []string{"a", "b"}},
+		{"at 2s", segmentTexts(tr.At(2 * time.Second)), []string{"b"}},
+	} {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %q; want %q", c.what, c.got, c.want)
+		}
+	}
+	if text := tr.Text(); text != "a b c d" {
+		t.Errorf("text is %q", text)
+	}
+	if got, want := describeOverlaps(tr.Overlaps), []string{"1-2 1,2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("overlaps are %q; want %q", got, want)
+	}
+	if r := tr.Result(); r.IsPartial || len(r.Segments) != 4 || len(r.SpeakerLabels) != 3 || len(r.Overlaps) != 1 {
+		t.Errorf("result is %v", r)
+	}
+}
+
+func TestAccumulator_Concurrent(t *testing.T) {
+	var acc juzu.Accumulator
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := time.Duration(i) * time.Second
+			acc.Handle(&juzupb.DiarizationResponse{Results: []*juzupb.DiarizationResult{{
+				Segments: []*juzupb.Segment{segment("1", start, start+time.Second, "")},
+			}}})
+			_ = acc.Transcript()
+		}(i)
+	}
+	wg.Wait()
+
+	segs := acc.Transcript().Segments
+	if len(segs) != 10 {
+		t.Fatalf("got %d segments; want 10", len(segs))
+	}
+	for i, s := range segs {
+		if s.StartTime.AsDuration() != time.Duration(i)*time.Second {
+			t.Errorf("segment %d starts at %v", i, s.StartTime.AsDuration())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vad
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/audio"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Timeline maps times in trimmed audio to times in the original audio.
+type Timeline struct {
+	mu         sync.Mutex
+	sampleRate int
+	cuts       []cut
+}
+
+// cut is a point of the trimmed audio where audio was removed.  Audio from
+// sample out of the trimmed audio on comes from sample orig of the original
+// audio on.
+type cut struct {
+	out, orig int64
+}
+
+func (tl *Timeline) add(out, orig int64) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	tl.cuts = append(tl.cuts, cut{out, orig})
+}
+
+// Original returns the time of the original audio that the time t of the
+// trimmed audio comes from.  A time at which audio was removed maps to the
+// end of the removed audio, which suits start times.
+func (tl *Timeline) Original(t time.Duration) time.Duration {
+	return tl.original(t, false)
+}
+
+// OriginalEnd is like Original, except that a time at which audio was removed
+// maps to the start of the removed audio, which suits end times.
+func (tl *Timeline) OriginalEnd(t time.Duration) time.Duration {
+	return tl.original(t, true)
+}
+
+func (tl *Timeline) original(t time.Duration, end bool) time.Duration {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	f := audio.Format{SampleRate: tl.sampleRate, Channels: 1}
+	at := func(samples int64) time.Duration {
+		return f.Duration(2 * samples)
+	}
+
+	// find the last cut before t, or at t for start times
+	i := sort.Search(len(tl.cuts), func(i int) bool {
+		c := at(tl.cuts[i].out)
+		if end {
+			return c >= t
+		}
+		return c > t
+	})
+	if i == 0 {
+		return t
+	}
+	c := tl.cuts[i-1]
+	return at(c.orig) + t - at(c.out)
+}
+
+// RemapResult changes the timestamps of the segments, words and overlap
+// regions of the result from times in the trimmed audio to times in the
+// original audio.
+func (tl *Timeline) RemapResult(r *juzupb.DiarizationResult) {
+	for _, s := range r.GetSegments() {
+		if s.StartTime != nil {
+			s.StartTime = durationpb.New(tl.Original(s.StartTime.AsDuration()))
+		}
+		if s.EndTime != nil {
+			s.EndTime = durationpb.New(tl.OriginalEnd(s.EndTime.AsDuration()))
+		}
+
+		for _, w := range s.Words {
+			if w.StartTime == nil {
+				continue
+			}
+			start := w.StartTime.AsDuration()
+			w.StartTime = durationpb.New(tl.Original(start))
+			if w.Duration != nil {
+				end := tl.OriginalEnd(start + w.Duration.AsDuration())
+				w.Duration = durationpb.New(end - w.StartTime.AsDuration())
+			}
+		}
+	}
+
+	for _, o := range r.GetOverlaps() {
+		if o.StartTime != nil {
+			o.StartTime = durationpb.New(tl.Original(o.StartTime.AsDuration()))
+		}
+		if o.EndTime != nil {
+			o.EndTime = durationpb.New(tl.OriginalEnd(o.EndTime.AsDuration()))
+		}
+	}
+}
+
+// RemapResponse remaps the timestamps of all results of the response.
+func (tl *Timeline) RemapResponse(resp *juzupb.DiarizationResponse) {
+	for _, r := range resp.GetResults() {
+		tl.RemapResult(r)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vad removes long silences from audio before it is sent to juzu
+// server, and maps the timestamps of the results back to the original audio.
+//
+// Silence is detected from the energy of short frames of audio.  Only
+// silences longer than a minimum duration are removed, and some silence is
+// kept on both sides of each removed silence so that speech is not clipped.
+package vad
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Defaults used for zero fields of Config.
+const (
+	DefaultFrameDuration = 20 * time.Millisecond
+	DefaultThreshold     = -40.0
+	DefaultMinSilence    = time.Second
+	DefaultPadding       = 250 * time.Millisecond
+)
+
+// Config configures a Trimmer.  Zero values select the defaults.
+type Config struct {
+	// Duration of the frames whose energy is measured.
+	FrameDuration time.Duration
+
+	// Frames whose RMS energy, in dB relative to full scale, is below this
+	// threshold are silent.  As for the other fields, zero selects the
+	// default, so a threshold of 0 dBFS, below which nearly all audio is,
+	// cannot be set.  Positive thresholds are invalid.
+	Threshold float64
+
+	// Silences shorter than this are kept.
+	MinSilence time.Duration
+
+	// Silence kept on each side of a removed silence.  Twice the padding
+	// must be shorter than MinSilence.
+	Padding time.Duration
+}
+
+func (c *Config) setDefaults() error {
+	if c.FrameDuration == 0 {
+		c.FrameDuration = DefaultFrameDuration
+	}
+	if c.Threshold == 0 {
+		c.Threshold = DefaultThreshold
+	}
+	if c.MinSilence == 0 {
+		c.MinSilence = DefaultMinSilence
+	}
+	if c.Padding == 0 {
+		c.Padding = DefaultPadding
+	}
+	if c.FrameDuration < 0 || c.Threshold > 0 || c.MinSilence < 0 || c.Padding < 0 || 2*c.Padding >= c.MinSilence {
+		return fmt.Errorf("invalid silence trimming config %+v", *c)
+	}
+	return nil
+}
+
+// Trimmer removes long silences from raw (headerless) 16-bit signed little
+// endian mono audio written to it, and writes the remaining audio to an
+// underlying writer.  Its Timeline maps times in the trimmed audio back to
+// the original audio.
+//
+// Audio is buffered while a silence may still turn out to be too short to be
+// removed, for up to the minimum silence duration.  Close must be called at
+// the end of the audio to write out what is left.
+type Trimmer struct {
+	w        io.Writer
+	timeline *Timeline
+
+	frameBytes int
+	threshold  float64 // on mean squared sample values
+	minSilence int     // frames
+	padding    int     // frames
+
+	frame    []byte  // frame being filled
+	inPos    int64   // bytes of original audio classified so far
+	outPos   int64   // bytes written out
+	nextOrig int64   // original offset of the audio following the last write
+	silent   int     // length of the current silence (frames)
+	removing bool    // whether the current silence is being removed
+	held     []frame // silent frames held back
+}
+
+// frame is a frame of audio and its offset in the original audio.
+type frame struct {
+	data []byte
+	orig int64
+}
+
+// NewTrimmer returns a Trimmer that writes audio of the given sample rate,
+// with long silences removed, to w.
+func NewTrimmer(w io.Writer, sampleRate int, cfg Config) (*Trimmer, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %d", sampleRate)
+	}
+	if err := cfg.setDefaults(); err != nil {
+		return nil, err
+	}
+
+	frameSamples := int(int64(sampleRate) * int64(cfg.FrameDuration) / int64(time.Second))
+	if frameSamples < 1 {
+		frameSamples = 1
+	}
+	frames := func(d time.Duration) int {
+		return int(d / cfg.FrameDuration)
+	}
+
+	// convert the threshold from dBFS to mean squared sample values
+	rms := math.Pow(10, cfg.Threshold/20) * math.MaxInt16
+
+	return &Trimmer{
+		w:          w,
+		timeline:   &Timeline{sampleRate: sampleRate},
+		frameBytes: 2 * frameSamples,
+		threshold:  rms * rms,
+		minSilence: frames(cfg.MinSilence),
+		padding:    frames(cfg.Padding),
+	}, nil
+}
+
+// Timeline returns the timeline of the trimmed audio.  It grows as audio is
+// written, and may be used concurrently with writing.
+func (t *Trimmer) Timeline() *Timeline {
+	return t.timeline
+}
+
+// Write trims the given audio and writes what is kept of it.
+func (t *Trimmer) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		m := t.frameBytes - len(t.frame)
+		if m > len(p) {
+			m = len(p)
+		}
+		t.frame = append(t.frame, p[:m]...)
+		p = p[m:]
+
+		if len(t.frame) == t.frameBytes {
+			if err := t.addFrame(t.frame); err != nil {
+				return 0, err
+			}
+			t.frame = nil
+		}
+	}
+	return n, nil
+}
+
+// Close processes the last, partial, frame, and writes out any held silence
+// that is short enough to be kept.  A long silence at the end of the audio
+// is removed except for its padding.  Close does not close the underlying
+// writer.
+func (t *Trimmer) Close() error {
+	if len(t.frame) > 0 {
+		if err := t.addFrame(t.frame); err != nil {
+			return err
+		}
+		t.frame = nil
+	}
+
+	if !t.removing {
+		return t.release()
+	}
+	t.held = nil
+	return nil
+}
+
+// addFrame classifies the frame as speech or silence and decides what to do
+// with it.  The first frames of a silence, up to the padding, are written out
+// straight away, since they are kept whatever the length of the silence.
+// Following frames are held until the silence ends or becomes long enough to
+// be removed; once it is being removed, only the last padding frames are held,
+// to be written out before the next speech.
+func (t *Trimmer) addFrame(data []byte) error {
+	f := frame{data: data, orig: t.inPos}
+	t.inPos += int64(len(data))
+
+	if !t.isSilent(data) {
+		if err := t.release(); err != nil {
+			return err
+		}
+		t.silent = 0
+		t.removing = false
+		return t.write(f)
+	}
+
+	t.silent++
+	if t.silent <= t.padding {
+		return t.write(f)
+	}
+
+	t.held = append(t.held, f)
+	if t.silent >= t.minSilence {
+		t.removing = true
+	}
+	if t.removing && len(t.held) > t.padding {
+		// drop the oldest held frames
+		n := len(t.held) - t.padding
+		for i := 0; i < n; i++ {
+			t.held[i] = frame{}
+		}
+		t.held = t.held[n:]
+	}
+	return nil
+}
+
+// release writes out all held frames.
+func (t *Trimmer) release() error {
+	for _, f := range t.held {
+		if err := t.write(f); err != nil {
+			return err
+		}
+	}
+	t.held = nil
+	return nil
+}
+
+// write writes out the given frame, and records on the timeline where audio
+// was removed before it.
+func (t *Trimmer) write(f frame) error {
+	if f.orig != t.nextOrig {
+		t.timeline.add(t.outPos/2, f.orig/2)
+	}
+
+	if _, err := t.w.Write(f.data); err != nil {
+		return err
+	}
+	t.outPos += int64(len(f.data))
+	t.nextOrig = f.orig + int64(len(f.data))
+	return nil
+}
+
+// isSilent reports whether the mean squared value of the samples of the frame
+// is below the threshold.
+func (t *Trimmer) isSilent(f []byte) bool {
+	var sum float64
+	n := len(f) / 2
+	if n == 0 {
+		return true
+	}
+	for i := 0; i < n; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(f[2*i:])))
+		sum += s * s
+	}
+	return sum/float64(n) < t.threshold
+}
@@ -0,0 +1,132 @@
+// Copyright (2021) Cobalt Speech and Language Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vad_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/juzupb"
+	"github.com/cobaltspeech/sdk-juzu/grpc/go-juzu/vad"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const rate = 8000
+
+// samples returns audio of the given duration.  Speech samples hold their
+// index in the audio, so that trimmed audio can be checked.
+func samples(speech bool, offset, d time.Duration) []byte {
+	start, n := int(offset*rate/time.Second), int(d*rate/time.Second)
+	b := make([]byte, 2*n)
+	for i := 0; speech && i < n; i++ {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(1000+(start+i)%20000))
+	}
+	return b
+}
+
+func TestTrimmer(t *testing.T) {
+	// speech at 0s, 1.5s and 5.5s, with silences of 0.5s (kept), 3s and 2s
+	// (trimmed) in between and at the end
+	type part struct {
+		speech bool
+		d      time.Duration
+	}
+	var in []byte
+	var at time.Duration
+	for _, p := range []part{{true, time.Second}, {false, 500 * time.Millisecond}, {true, time.Second},
+		{false, 3 * time.Second}, {true, time.Second}, {false, 2 * time.Second}} {
+		in = append(in, samples(p.speech, at, p.d)...)
+		at += p.d
+	}
+
+	var out bytes.Buffer
+	tr, err := vad.NewTrimmer(&out, rate, vad.Config{FrameDuration: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("could not create trimmer: %v", err)
+	}
+
+	// write in pieces that do not match the frames
+	for p := in; len(p) > 0; {
+		n := 333
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := tr.Write(p[:n]); err != nil {
+			t.Fatalf("could not write to trimmer: %v", err)
+		}
+		p = p[n:]
+	}
+	if err := tr.Close(); err != nil {
+		t.Fatalf("could not close trimmer: %v", err)
+	}
+
+	// The first 2.75s are kept, followed by the 1.5s from 5.25s on.
+	want := append(append([]byte(nil), in[:2*2750*rate/1000]...), in[2*5250*rate/1000:2*6750*rate/1000]...)
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("trimmed audio is %v long; want %v of expected audio", time.Duration(out.Len()/2)*time.Second/rate,
+			time.Duration(len(want)/2)*time.Second/rate)
+	}
+
+	tl := tr.Timeline()
+	for _, tc := range []struct {
+		t, start, end time.Duration
+	}{
+		{time.Second, time.Second, time.Second},
+		{2750 * time.Millisecond, 5250 * time.Millisecond, 2750 * time.Millisecond},
+		{3 * time.Second, 5500 * time.Millisecond, 5500 * time.Millisecond},
+	} {
+		if got := tl.Original(tc.t); got != tc.start {
+			t.Errorf("original start time of %v is %v; want %v", tc.t, got, tc.start)
+		}
+		if got := tl.OriginalEnd(tc.t); got != tc.end {
+			t.Errorf("original end time of %v is %v; want %v", tc.t, got, tc.end)
+		}
+	}
+
+	// a word spanning the removed silence keeps its start, and ends at the
+	// same point of the original audio
+	r := &juzupb.DiarizationResult{Segments: []*juzupb.Segment{{
+		StartTime: durationpb.New(2500 * time.Millisecond),
+		EndTime:   durationpb.New(3 * time.Second),
+		Words: []*juzupb.WordInfo{{
+			StartTime: durationpb.New(2500 * time.Millisecond),
+			Duration:  durationpb.New(500 * time.Millisecond),
+		}},
+	}}, Overlaps: []*juzupb.OverlapRegion{{
+		StartTime:     durationpb.New(2750 * time.Millisecond),
+		EndTime:       durationpb.New(3 * time.Second),
+		SpeakerLabels: []string{"1", "2"},
+	}}}
+	tl.RemapResult(r)
+	s := r.Segments[0]
+	if s.StartTime.AsDuration() != 2500*time.Millisecond || s.EndTime.AsDuration() != 5500*time.Millisecond {
+		t.Errorf("remapped segment is %v-%v; want 2.5s-5.5s", s.StartTime.AsDuration(), s.EndTime.AsDuration())
+	}
+	if w := s.Words[0]; w.StartTime.AsDuration() != 2500*time.Millisecond || w.Duration.AsDuration() != 3*time.Second {
+		t.Errorf("remapped word is %v+%v; want 2.5s+3s", w.StartTime.AsDuration(), w.Duration.AsDuration())
+	}
+	if o := r.Overlaps[0]; o.StartTime.AsDuration() != 5250*time.Millisecond || o.EndTime.AsDuration() != 5500*time.Millisecond {
+		t.Errorf("remapped overlap is %v-%v; want 5.25s-5.5s", o.StartTime.AsDuration(), o.EndTime.AsDuration())
+	}
+
+	if _, err := vad.NewTrimmer(&out, rate, vad.Config{MinSilence: time.Second, Padding: time.Second}); err == nil {
+		t.Errorf("creating trimmer with padding longer than half the minimum silence: want error, got nil")
+	}
+	if _, err := vad.NewTrimmer(&out, rate, vad.Config{Threshold: 3}); err == nil {
+		t.Errorf("creating trimmer with positive threshold: want error, got nil")
+	}
+}
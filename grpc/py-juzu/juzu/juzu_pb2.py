@@ -22,7 +22,7 @@ DESCRIPTOR = _descriptor.FileDescriptor(
   syntax='proto3',
   serialized_options=b'Z\t./;juzupb\252\002\021CobaltSpeech.Juzu',
   create_key=_descriptor._internal_create_key,
-  serialized_pb=b'\n\njuzu.proto\x12\x11\x63obaltspeech.juzu\x1a\x1cgoogle/api/annotations.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x92\x01\n\x17StreamingDiarizeRequest\x12\x36\n\x06\x63onfig\x18\x01 \x01(\x0b\x32$.cobaltspeech.juzu.DiarizationConfigH\x00\x12\x34\n\x05\x61udio\x18\x02 \x01(\x0b\x32#.cobaltspeech.juzu.DiarizationAudioH\x00\x42\t\n\x07request\"/\n\x0fVersionResponse\x12\x0c\n\x04juzu\x18\x01 \x01(\t\x12\x0e\n\x06server\x18\x02 \x01(\t\">\n\x12ListModelsResponse\x12(\n\x06models\x18\x01 \x03(\x0b\x32\x18.cobaltspeech.juzu.Model\"Y\n\x05Model\x12\n\n\x02id\x18\x01 \x01(\t\x12\x0c\n\x04name\x18\x02 \x01(\t\x12\x36\n\nattributes\x18\x03 \x01(\x0b\x32\".cobaltspeech.juzu.ModelAttributes\"b\n\x0fModelAttributes\x12\x13\n\x0bsample_rate\x18\x01 \x01(\r\x12\x19\n\x11segmentation_type\x18\x02 \x01(\t\x12\x1f\n\x17\x63ompatible_cubic_models\x18\x03 \x03(\t\"L\n\x13\x44iarizationResponse\x12\x35\n\x07results\x18\x01 \x03(\x0b\x32$.cobaltspeech.juzu.DiarizationResult\"\x88\x02\n\x11\x44iarizationConfig\x12\x10\n\x08model_id\x18\x01 \x01(\t\x12\x14\n\x0cnum_speakers\x18\x02 \x01(\r\x12\x13\n\x0bsample_rate\x18\x03 \x01(\r\x12\x45\n\x0e\x61udio_encoding\x18\x04 \x01(\x0e\x32-.cobaltspeech.juzu.DiarizationConfig.Encoding\x12\x16\n\x0e\x63ubic_model_id\x18\x05 \x01(\t\x12\x1d\n\x15\x65nable_raw_transcript\x18\x06 \x01(\x08\"8\n\x08\x45ncoding\x12\x10\n\x0cRAW_LINEAR16\x10\x00\x12\x07\n\x03WAV\x10\x01\x12\x08\n\x04\x46LAC\x10\x02\x12\x07\n\x03MP3\x10\x03\" \n\x10\x44iarizationAudio\x12\x0c\n\x04\x64\x61ta\x18\x01 \x01(\x0c\"m\n\x11\x44iarizationResult\x12,\n\x08segments\x18\x01 \x03(\x0b\x32\x1a.cobaltspeech.juzu.Segment\x12\x16\n\x0espeaker_labels\x18\x02 \x03(\t\x12\x12\n\nis_partial\x18\x03 \x01(\x08\"\xbc\x01\n\x07Segment\x12\x15\n\rspeaker_label\x18\x01 \x01(\t\x12-\n\nstart_time\x18\x02 \x01(\x0b\x32\x19.google.protobuf.Duration\x12+\n\x08\x65nd_time\x18\x03 \x01(\x0b\x32\x19.google.protobuf.Duration\x12\x12\n\ntranscript\x18\x04 \x01(\t\x12*\n\x05words\x18\x05 \x03(\x0b\x32\x1b.cobaltspeech.juzu.WordInfo\"\x88\x01\n\x08WordInfo\x12\x0c\n\x04word\x18\x01 \x01(\t\x12\x12\n\nconfidence\x18\x02 \x01(\x01\x12-\n\nstart_time\x18\x03 \x01(\x0b\x32\x19.google.protobuf.Duration\x12+\n\x08\x64uration\x18\x04 \x01(\x0b\x32\x19.google.protobuf.Duration2\xcc\x02\n\x04Juzu\x12[\n\x07Version\x12\x16.google.protobuf.Empty\x1a\".cobaltspeech.juzu.VersionResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\x0c/api/version\x12\x64\n\nListModels\x12\x16.google.protobuf.Empty\x1a%.cobaltspeech.juzu.ListModelsResponse\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/api/listmodels\x12\x80\x01\n\x10StreamingDiarize\x12*.cobaltspeech.juzu.StreamingDiarizeRequest\x1a&.cobaltspeech.juzu.DiarizationResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\x0c/api/diarize(\x01\x30\x01\x42\x1fZ\t./;juzupb\xaa\x02\x11\x43obaltSpeech.Juzub\x06proto3'
+  serialized_pb=b'\n\njuzu.proto\x12\x11\x63obaltspeech.juzu\x1a\x1cgoogle/api/annotations.proto\x1a\x1egoogle/protobuf/duration.proto\x1a\x1bgoogle/protobuf/empty.proto\"\x92\x01\n\x17StreamingDiarizeRequest\x12\x36\n\x06\x63onfig\x18\x01 \x01(\x0b\x32$.cobaltspeech.juzu.DiarizationConfigH\x00\x12\x34\n\x05\x61udio\x18\x02 \x01(\x0b\x32#.cobaltspeech.juzu.DiarizationAudioH\x00\x42\t\n\x07request\"/\n\x0fVersionResponse\x12\x0c\n\x04juzu\x18\x01 \x01(\t\x12\x0e\n\x06server\x18\x02 \x01(\t\">\n\x12ListModelsResponse\x12(\n\x06models\x18\x01 \x03(\x0b\x32\x18.cobaltspeech.juzu.Model\"Y\n\x05Model\x12\n\n\x02id\x18\x01 \x01(\t\x12\x0c\n\x04name\x18\x02 \x01(\t\x12\x36\n\nattributes\x18\x03 \x01(\x0b\x32\".cobaltspeech.juzu.ModelAttributes\"b\n\x0fModelAttributes\x12\x13\n\x0bsample_rate\x18\x01 \x01(\r\x12\x19\n\x11segmentation_type\x18\x02 \x01(\t\x12\x1f\n\x17\x63ompatible_cubic_models\x18\x03 \x03(\t\"L\n\x13\x44iarizationResponse\x12\x35\n\x07results\x18\x01 \x03(\x0b\x32$.cobaltspeech.juzu.DiarizationResult\"\x88\x02\n\x11\x44iarizationConfig\x12\x10\n\x08model_id\x18\x01 \x01(\t\x12\x14\n\x0cnum_speakers\x18\x02 \x01(\r\x12\x13\n\x0bsample_rate\x18\x03 \x01(\r\x12\x45\n\x0e\x61udio_encoding\x18\x04 \x01(\x0e\x32-.cobaltspeech.juzu.DiarizationConfig.Encoding\x12\x16\n\x0e\x63ubic_model_id\x18\x05 \x01(\t\x12\x1d\n\x15\x65nable_raw_transcript\x18\x06 \x01(\x08\"8\n\x08\x45ncoding\x12\x10\n\x0cRAW_LINEAR16\x10\x00\x12\x07\n\x03WAV\x10\x01\x12\x08\n\x04\x46LAC\x10\x02\x12\x07\n\x03MP3\x10\x03\" \n\x10\x44iarizationAudio\x12\x0c\n\x04\x64\x61ta\x18\x01 \x01(\x0c\"\xa1\x01\n\x11\x44iarizationResult\x12,\n\x08segments\x18\x01 \x03(\x0b\x32\x1a.cobaltspeech.juzu.Segment\x12\x16\n\x0espeaker_labels\x18\x02 \x03(\t\x12\x12\n\nis_partial\x18\x03 \x01(\x08\x12\x32\n\x08overlaps\x18\x04 \x03(\x0b\x32 .cobaltspeech.juzu.OverlapRegion\"\xbc\x01\n\x07Segment\x12\x15\n\rspeaker_label\x18\x01 \x01(\t\x12-\n\nstart_time\x18\x02 \x01(\x0b\x32\x19.google.protobuf.Duration\x12+\n\x08\x65nd_time\x18\x03 \x01(\x0b\x32\x19.google.protobuf.Duration\x12\x12\n\ntranscript\x18\x04 \x01(\t\x12*\n\x05words\x18\x05 \x03(\x0b\x32\x1b.cobaltspeech.juzu.WordInfo\"\x83\x01\n\rOverlapRegion\x12-\n\nstart_time\x18\x01 \x01(\x0b\x32\x19.google.protobuf.Duration\x12+\n\x08\x65nd_time\x18\x02 \x01(\x0b\x32\x19.google.protobuf.Duration\x12\x16\n\x0espeaker_labels\x18\x03 \x03(\t\"\x88\x01\n\x08WordInfo\x12\x0c\n\x04word\x18\x01 \x01(\t\x12\x12\n\nconfidence\x18\x02 \x01(\x01\x12-\n\nstart_time\x18\x03 \x01(\x0b\x32\x19.google.protobuf.Duration\x12+\n\x08\x64uration\x18\x04 \x01(\x0b\x32\x19.google.protobuf.Duration2\xcc\x02\n\x04Juzu\x12[\n\x07Version\x12\x16.google.protobuf.Empty\x1a\".cobaltspeech.juzu.VersionResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\x0c/api/version\x12\x64\n\nListModels\x12\x16.google.protobuf.Empty\x1a%.cobaltspeech.juzu.ListModelsResponse\"\x17\x82\xd3\xe4\x93\x02\x11\x12\x0f/api/listmodels\x12\x80\x01\n\x10StreamingDiarize\x12*.cobaltspeech.juzu.StreamingDiarizeRequest\x1a&.cobaltspeech.juzu.DiarizationResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\x0c/api/diarize(\x01\x30\x01\x42\x1fZ\t./;juzupb\xaa\x02\x11\x43obaltSpeech.Juzub\x06proto3'
   ,
   dependencies=[google_dot_api_dot_annotations__pb2.DESCRIPTOR,google_dot_protobuf_dot_duration__pb2.DESCRIPTOR,google_dot_protobuf_dot_empty__pb2.DESCRIPTOR,])
 
@@ -432,6 +432,13 @@ _DIARIZATIONRESULT = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
+    _descriptor.FieldDescriptor(
+      name='overlaps', full_name='cobaltspeech.juzu.DiarizationResult.overlaps', index=3,
+      number=4, type=11, cpp_type=10, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
   ],
   extensions=[
   ],
@@ -444,8 +451,8 @@ _DIARIZATIONRESULT = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=956,
-  serialized_end=1065,
+  serialized_start=957,
+  serialized_end=1118,
 )
 
 
@@ -504,8 +511,54 @@ _SEGMENT = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1068,
-  serialized_end=1256,
+  serialized_start=1121,
+  serialized_end=1309,
+)
+
+
+_OVERLAPREGION = _descriptor.Descriptor(
+  name='OverlapRegion',
+  full_name='cobaltspeech.juzu.OverlapRegion',
+  filename=None,
+  file=DESCRIPTOR,
+  containing_type=None,
+  create_key=_descriptor._internal_create_key,
+  fields=[
+    _descriptor.FieldDescriptor(
+      name='start_time', full_name='cobaltspeech.juzu.OverlapRegion.start_time', index=0,
+      number=1, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
+    _descriptor.FieldDescriptor(
+      name='end_time', full_name='cobaltspeech.juzu.OverlapRegion.end_time', index=1,
+      number=2, type=11, cpp_type=10, label=1,
+      has_default_value=False, default_value=None,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
+    _descriptor.FieldDescriptor(
+      name='speaker_labels', full_name='cobaltspeech.juzu.OverlapRegion.speaker_labels', index=2,
+      number=3, type=9, cpp_type=9, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
+  ],
+  extensions=[
+  ],
+  nested_types=[],
+  enum_types=[
+  ],
+  serialized_options=None,
+  is_extendable=False,
+  syntax='proto3',
+  extension_ranges=[],
+  oneofs=[
+  ],
+  serialized_start=1312,
+  serialized_end=1443,
 )
 
 
@@ -557,8 +610,8 @@ _WORDINFO = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=1259,
-  serialized_end=1395,
+  serialized_start=1446,
+  serialized_end=1582,
 )
 
 _STREAMINGDIARIZEREQUEST.fields_by_name['config'].message_type = _DIARIZATIONCONFIG
@@ -575,9 +628,12 @@ _DIARIZATIONRESPONSE.fields_by_name['results'].message_type = _DIARIZATIONRESULT
 _DIARIZATIONCONFIG.fields_by_name['audio_encoding'].enum_type = _DIARIZATIONCONFIG_ENCODING
 _DIARIZATIONCONFIG_ENCODING.containing_type = _DIARIZATIONCONFIG
 _DIARIZATIONRESULT.fields_by_name['segments'].message_type = _SEGMENT
+_DIARIZATIONRESULT.fields_by_name['overlaps'].message_type = _OVERLAPREGION
 _SEGMENT.fields_by_name['start_time'].message_type = google_dot_protobuf_dot_duration__pb2._DURATION
 _SEGMENT.fields_by_name['end_time'].message_type = google_dot_protobuf_dot_duration__pb2._DURATION
 _SEGMENT.fields_by_name['words'].message_type = _WORDINFO
+_OVERLAPREGION.fields_by_name['start_time'].message_type = google_dot_protobuf_dot_duration__pb2._DURATION
+_OVERLAPREGION.fields_by_name['end_time'].message_type = google_dot_protobuf_dot_duration__pb2._DURATION
 _WORDINFO.fields_by_name['start_time'].message_type = google_dot_protobuf_dot_duration__pb2._DURATION
 _WORDINFO.fields_by_name['duration'].message_type = google_dot_protobuf_dot_duration__pb2._DURATION
 DESCRIPTOR.message_types_by_name['StreamingDiarizeRequest'] = _STREAMINGDIARIZEREQUEST
@@ -590,6 +646,7 @@ DESCRIPTOR.message_types_by_name['DiarizationConfig'] = _DIARIZATIONCONFIG
 DESCRIPTOR.message_types_by_name['DiarizationAudio'] = _DIARIZATIONAUDIO
 DESCRIPTOR.message_types_by_name['DiarizationResult'] = _DIARIZATIONRESULT
 DESCRIPTOR.message_types_by_name['Segment'] = _SEGMENT
+DESCRIPTOR.message_types_by_name['OverlapRegion'] = _OVERLAPREGION
 DESCRIPTOR.message_types_by_name['WordInfo'] = _WORDINFO
 _sym_db.RegisterFileDescriptor(DESCRIPTOR)
 
@@ -663,6 +720,13 @@ Segment = _reflection.GeneratedProtocolMessageType('Segment', (_message.Message,
   })
 _sym_db.RegisterMessage(Segment)
 
+OverlapRegion = _reflection.GeneratedProtocolMessageType('OverlapRegion', (_message.Message,), {
+  'DESCRIPTOR' : _OVERLAPREGION,
+  '__module__' : 'juzu_pb2'
+  # @@protoc_insertion_point(class_scope:cobaltspeech.juzu.OverlapRegion)
+  })
+_sym_db.RegisterMessage(OverlapRegion)
+
 WordInfo = _reflection.GeneratedProtocolMessageType('WordInfo', (_message.Message,), {
   'DESCRIPTOR' : _WORDINFO,
   '__module__' : 'juzu_pb2'
@@ -680,8 +744,8 @@ _JUZU = _descriptor.ServiceDescriptor(
   index=0,
   serialized_options=None,
   create_key=_descriptor._internal_create_key,
-  serialized_start=1398,
-  serialized_end=1730,
+  serialized_start=1585,
+  serialized_end=1917,
   methods=[
   _descriptor.MethodDescriptor(
     name='Version',